@@ -0,0 +1,293 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+// Package client implements a client for the coderator HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/trubitsyn/coderator/coderator"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Client struct {
+	Server     string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// APIError is returned when the server responds with an unexpected status.
+type APIError struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+func New(server string) *Client {
+	return &Client{
+		Server: strings.TrimRight(server, "/"),
+		HTTPClient: &http.Client{
+			CheckRedirect: func(request *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func (c *Client) Tasks() ([]coderator.Task, error) {
+	tasks := make([]coderator.Task, 0)
+	err := c.do(context.Background(), "GET", coderator.PathTasks, nil, "", http.StatusOK, &tasks)
+	return tasks, err
+}
+
+func (c *Client) Task(id uint64) (*coderator.TaskDetails, error) {
+	task := coderator.TaskDetails{}
+	err := c.do(context.Background(), "GET", path(coderator.PathTask, id), nil, "", http.StatusOK, &task)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (c *Client) Tests(taskId uint64) ([]coderator.Test, error) {
+	tests := make([]coderator.Test, 0)
+	err := c.do(context.Background(), "GET", path(coderator.PathTests, taskId), nil, "", http.StatusOK, &tests)
+	return tests, err
+}
+
+// Template returns the starter code of the task, lang being the name of the
+// processor or empty for the default processor of the task.
+func (c *Client) Template(taskId uint64, lang string) (*coderator.Template, error) {
+	template := coderator.Template{}
+	err := c.do(context.Background(), "GET", path(coderator.PathTemplate, taskId)+"?lang="+url.QueryEscape(lang), nil, "", http.StatusOK, &template)
+	if err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (c *Client) Processors() ([]coderator.LanguageProcessor, error) {
+	processors := make([]coderator.LanguageProcessor, 0)
+	err := c.do(context.Background(), "GET", coderator.PathProcessors, nil, "", http.StatusOK, &processors)
+	return processors, err
+}
+
+func (c *Client) Submission(id uint64) (*coderator.Submission, error) {
+	submission := coderator.Submission{}
+	err := c.do(context.Background(), "GET", path(coderator.PathSubmission, id), nil, "", http.StatusOK, &submission)
+	if err != nil {
+		return nil, err
+	}
+	return &submission, nil
+}
+
+// Submit uploads the source as a solution of the task and returns the
+// location of the queued verification. An empty processor is detected by
+// the server.
+func (c *Client) Submit(taskId uint64, processor string, filename string, source io.Reader) (string, error) {
+	data, err := ioutil.ReadAll(source)
+	if err != nil {
+		return "", err
+	}
+	return c.SubmitFiles(taskId, processor, []coderator.SourceFile{{Name: filename, Data: data}})
+}
+
+// SubmitFiles uploads several files or an archive as a solution of the task.
+func (c *Client) SubmitFiles(taskId uint64, processor string, files []coderator.SourceFile) (string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if processor != "" {
+		if err := writer.WriteField("processor", processor); err != nil {
+			return "", err
+		}
+	}
+	for _, file := range files {
+		part, err := writer.CreateFormFile("source", file.Name)
+		if err != nil {
+			return "", err
+		}
+		if _, err = part.Write(file.Data); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	response, err := c.request(context.Background(), "POST", path(coderator.PathSolve, taskId), body, writer.FormDataContentType())
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusAccepted {
+		return "", apiError(response)
+	}
+	return response.Header.Get("Location"), nil
+}
+
+// Wait polls the queue location until the verification completes and
+// follows the redirect to the submission. It gives up when the context is
+// done.
+func (c *Client) Wait(ctx context.Context, location string, interval time.Duration) (*coderator.Submission, error) {
+	for {
+		response, err := c.request(ctx, "GET", location, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		response.Body.Close()
+
+		switch response.StatusCode {
+		case http.StatusAccepted, http.StatusProcessing:
+			if err = sleep(ctx, interval); err != nil {
+				return nil, err
+			}
+		case http.StatusSeeOther:
+			submission := coderator.Submission{}
+			err = c.do(ctx, "GET", response.Header.Get("Location"), nil, "", http.StatusOK, &submission)
+			if err != nil {
+				return nil, err
+			}
+			return &submission, nil
+		default:
+			return nil, apiError(response)
+		}
+	}
+}
+
+// WaitForSubmission polls the submission until its verdict is available or
+// the context is done.
+func (c *Client) WaitForSubmission(ctx context.Context, id uint64, interval time.Duration) (*coderator.Submission, error) {
+	for {
+		submission := coderator.Submission{}
+		err := c.do(ctx, "GET", path(coderator.PathSubmission, id), nil, "", http.StatusOK, &submission)
+		if err != nil {
+			return nil, err
+		}
+		if coderator.HasVerificationCompleted(submission) {
+			return &submission, nil
+		}
+		if err = sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *Client) RejudgeSubmission(id uint64) ([]uint64, error) {
+	return c.rejudge(path(coderator.PathRejudgeSubmission, id))
+}
+
+func (c *Client) RejudgeTask(id uint64) ([]uint64, error) {
+	return c.rejudge(path(coderator.PathRejudgeTask, id))
+}
+
+func (c *Client) RejudgeContest(id uint64) ([]uint64, error) {
+	return c.rejudge(path(coderator.PathRejudgeContest, id))
+}
+
+func (c *Client) rejudge(path string) ([]uint64, error) {
+	ids := make([]uint64, 0)
+	err := c.do(context.Background(), "POST", path, nil, "", http.StatusAccepted, &ids)
+	return ids, err
+}
+
+func (c *Client) do(ctx context.Context, method string, path string, body io.Reader, contentType string, status int, value interface{}) error {
+	response, err := c.request(ctx, method, path, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != status {
+		return apiError(response)
+	}
+	return json.NewDecoder(response.Body).Decode(value)
+}
+
+func (c *Client) request(ctx context.Context, method string, path string, body io.Reader, contentType string) (*http.Response, error) {
+	target, err := url.Parse(c.Server)
+	if err != nil {
+		return nil, err
+	}
+	reference, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(method, target.ResolveReference(reference).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	request = request.WithContext(ctx)
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(request)
+}
+
+func apiError(response *http.Response) error {
+	message := coderator.Error{}
+	json.NewDecoder(response.Body).Decode(&message)
+	if message.Error == "" {
+		message.Error = response.Status
+	}
+
+	err := &APIError{StatusCode: response.StatusCode, Message: message.Error}
+	if seconds, parseErr := strconv.Atoi(response.Header.Get("Retry-After")); parseErr == nil {
+		err.RetryAfter = time.Duration(seconds) * time.Second
+	}
+	return err
+}
+
+func sleep(ctx context.Context, interval time.Duration) error {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func path(template string, id uint64) string {
+	return strings.Replace(template, "{id}", fmt.Sprint(id), 1)
+}
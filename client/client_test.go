@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/trubitsyn/coderator/coderator"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubmitFiles(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/tasks/1/solve" || r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("%s %s %q", r.Method, r.URL.Path, r.Header.Get("Authorization"))
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+		sources := r.MultipartForm.File["source"]
+		if r.FormValue("processor") != "python3.6" || len(sources) != 2 || sources[0].Filename != "main.py" || !strings.Contains(sources[1].Header.Get("Content-Disposition"), `filename="lib/util.py"`) {
+			t.Errorf("%v %+v", r.MultipartForm.Value, sources)
+		}
+		file, _ := sources[0].Open()
+		if data, _ := ioutil.ReadAll(file); string(data) != "print(1)" {
+			t.Errorf("%q", data)
+		}
+		w.Header().Set("Location", "/queue/5")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	c := New(server.URL)
+	c.Token = "secret"
+	files := []coderator.SourceFile{{Name: "main.py", Data: []byte("print(1)")}, {Name: "lib/util.py", Data: []byte("")}}
+	location, err := c.SubmitFiles(1, "python3.6", files)
+	if err != nil || location != "/queue/5" {
+		t.Errorf("%q %v", location, err)
+	}
+}
+
+func TestWait(t *testing.T) {
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/queue/5":
+			polls++
+			if polls < 3 {
+				w.WriteHeader(http.StatusAccepted)
+				return
+			}
+			w.Header().Set("Location", "/submissions/5")
+			w.WriteHeader(http.StatusSeeOther)
+		case "/submissions/5":
+			submission := coderator.Submission{Result: coderator.Success, Results: []bool{true}}
+			submission.Id = 5
+			json.NewEncoder(w).Encode(submission)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	submission, err := New(server.URL).Wait(context.Background(), "/queue/5", time.Millisecond)
+	if err != nil || polls != 3 || submission.Id != 5 || submission.Result != coderator.Success {
+		t.Errorf("%+v %v %d", submission, err, polls)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := New(server.URL).Wait(ctx, "/queue/5", 10*time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("%v", err)
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(coderator.Error{Error: coderator.ErrorCooldown})
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL).SubmitFiles(1, "", nil)
+	apiError, ok := err.(*APIError)
+	if !ok || apiError.StatusCode != http.StatusTooManyRequests || apiError.Message != coderator.ErrorCooldown || apiError.RetryAfter != 7*time.Second {
+		t.Errorf("%#v", err)
+	}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/trubitsyn/coderator/coderator"
+	"os"
+	"sort"
+)
+
+func calibrate(args []string) int {
+	flags := flag.NewFlagSet("calibrate", flag.ExitOnError)
+	config := flags.String("config", "serve.yml", "Application config")
+	dryRun := flags.Bool("dry-run", false, "Only print the proposed time limits")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: coderator calibrate [-config serve.yml] [-dry-run] <task.yml>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+
+	taskConfig, err := coderator.LoadTaskConfig(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	appConfig, err := coderator.LoadApplicationConfig(*config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	limits, measurements, err := coderator.CalibrateTimeLimits(*taskConfig, *appConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	fmt.Printf("%-40s %-12s %s\n", "SOLUTION", "PROCESSOR", "TIME")
+	for _, measurement := range measurements {
+		fmt.Printf("%-40s %-12s %s\n", measurement.Solution.Path, measurement.Solution.Processor, measurement.Time)
+	}
+
+	names := make([]string, 0, len(limits))
+	for name := range limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("\n%-12s %s\n", "PROCESSOR", "TIME LIMIT")
+	for _, name := range names {
+		fmt.Printf("%-12s %s\n", name, limits[name])
+	}
+
+	if *dryRun {
+		return 0
+	}
+	if err = taskConfig.WriteTimeLimits(limits); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Println("\nTime limits written to " + flags.Arg(0))
+	return 0
+}
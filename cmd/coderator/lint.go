@@ -0,0 +1,64 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/trubitsyn/coderator/coderator"
+	"os"
+)
+
+func lint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	config := flags.String("config", "serve.yml", "Application config")
+	solutions := flags.Bool("solutions", false, "Run the solutions of the tasks and check their verdicts")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: coderator lint [-config serve.yml] [-solutions] [tasks directory]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	dir := coderator.TasksDir
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	appConfig, err := coderator.LoadApplicationConfig(*config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	problems := coderator.LintTasks(dir, *appConfig)
+	if len(problems) == 0 {
+		problems = coderator.ValidateAllInputs(dir, *appConfig)
+	}
+	if *solutions && len(problems) == 0 {
+		problems = coderator.CheckAllSolutions(dir, *appConfig)
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	return 0
+}
@@ -23,13 +23,32 @@ import (
 	"flag"
 	"fmt"
 	"github.com/trubitsyn/coderator/coderator"
+	"os"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "test":
+			os.Exit(test(os.Args[2:]))
+		case "submit":
+			os.Exit(submit(os.Args[2:]))
+		case "rejudge":
+			os.Exit(rejudge(os.Args[2:]))
+		case "lint":
+			os.Exit(lint(os.Args[2:]))
+		case "calibrate":
+			os.Exit(calibrate(os.Args[2:]))
+		case "stress":
+			os.Exit(stress(os.Args[2:]))
+		}
+	}
+
 	fmt.Println("coderator started.")
 
 	port := flag.Int("port", 8080, "Port")
 	useDatabase := flag.Bool("database", false, "Use relational database")
+	allowInvalid := flag.Bool("allow-invalid-tasks", false, "Start even if task files are invalid")
 
 	flag.Parse()
 
@@ -44,7 +63,36 @@ func main() {
 
 		dataSource = db
 	} else {
-		dataSource = coderator.Config{}
+		config := coderator.Config{}
+		appConfig, err := config.ApplicationConfig()
+		if err != nil {
+			panic(err)
+		}
+
+		problems := coderator.LintTasks(coderator.TasksDir, *appConfig)
+		if len(problems) == 0 {
+			problems = coderator.ValidateAllInputs(coderator.TasksDir, *appConfig)
+		}
+		// The tests of the valid tasks are generated even when others are
+		// invalid, for the server to judge them under -allow-invalid-tasks.
+		reported := make(map[string]bool)
+		for _, problem := range problems {
+			reported[problem.File] = true
+		}
+		for _, problem := range coderator.MaterializeAllTests(coderator.TasksDir, *appConfig) {
+			if !reported[problem.File] {
+				problems = append(problems, problem)
+			}
+		}
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		if len(problems) > 0 && !*allowInvalid {
+			fmt.Fprintln(os.Stderr, "Invalid task files, run with -allow-invalid-tasks to start anyway")
+			os.Exit(1)
+		}
+
+		dataSource = config
 	}
 
 	coderator.Serve(dataSource, *port)
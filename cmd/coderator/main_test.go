@@ -0,0 +1,49 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	serve := filepath.Join(dir, "serve.yml")
+	task := filepath.Join(dir, "double.yml")
+	ioutil.WriteFile(serve, []byte("processors:\n  - name: sh\n    path: /bin/sh\n"), 0644)
+	ioutil.WriteFile(task, []byte("task:\n  id: 1\n  title: Double\n  processor: sh\n\ntests:\n  - input: \"2\"\n    output: \"4\"\n  - input: \"3\"\n    output: \"6\"\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "double.sh"), []byte("echo $(($1 * 2))\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "square.sh"), []byte("echo $(($1 * $1))\n"), 0644)
+
+	if code := test([]string{"-config", serve, task, filepath.Join(dir, "double.sh")}); code != 0 {
+		t.Errorf("passing solution exited with %d", code)
+	}
+	if code := test([]string{"-config", serve, task, filepath.Join(dir, "square.sh")}); code != 1 {
+		t.Errorf("failing solution exited with %d", code)
+	}
+}
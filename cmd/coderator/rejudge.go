@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/trubitsyn/coderator/client"
+	"github.com/trubitsyn/coderator/coderator"
+	"os"
+	"strings"
+	"time"
+)
+
+func rejudge(args []string) int {
+	flags := flag.NewFlagSet("rejudge", flag.ExitOnError)
+	server := flags.String("server", "http://localhost:8080", "Server URL")
+	token := flags.String("token", os.Getenv("CODERATOR_TOKEN"), "Administrator token")
+	submission := flags.Uint64("submission", 0, "Rejudge the submission")
+	task := flags.Uint64("task", 0, "Rejudge all submissions of the task")
+	contest := flags.Uint64("contest", 0, "Rejudge all submissions in the contest")
+	wait := flags.Bool("wait", true, "Wait for the verdicts and show the changes")
+	timeout := flags.Duration("timeout", 10*time.Minute, "Maximum time to wait for the verdicts")
+	flags.Parse(args)
+
+	c := client.New(*server)
+	c.Token = *token
+
+	var ids []uint64
+	var err error
+	switch {
+	case *submission != 0:
+		ids, err = c.RejudgeSubmission(*submission)
+	case *task != 0:
+		ids, err = c.RejudgeTask(*task)
+	case *contest != 0:
+		ids, err = c.RejudgeContest(*contest)
+	default:
+		fmt.Fprintln(os.Stderr, "One of -submission, -task or -contest is required")
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if !*wait {
+		for _, id := range ids {
+			fmt.Println(id)
+		}
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	fmt.Printf("%-10s %-16s %-16s %s\n", "SUBMISSION", "BEFORE", "AFTER", "CHANGED TESTS")
+	for _, id := range ids {
+		submission, err := c.WaitForSubmission(ctx, id, time.Second)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		before := coderator.Verdict{}
+		if len(submission.History) > 0 {
+			before = submission.History[len(submission.History)-1]
+		}
+		changed := changedTests(before.Results, submission.Results)
+		fmt.Printf("%-10d %-16s %-16s %s\n", id, before.Result, submission.Result, strings.Join(changed, " "))
+	}
+	return 0
+}
+
+// changedTests lists the tests whose results differ, marking each with the
+// new result.
+func changedTests(before []bool, after []bool) []string {
+	changed := make([]string, 0)
+	for i := 0; i < len(before) || i < len(after); i++ {
+		was := i < len(before) && before[i]
+		is := i < len(after) && after[i]
+		if was != is || i >= len(before) {
+			mark := "-"
+			if is {
+				mark = "+"
+			}
+			changed = append(changed, fmt.Sprintf("%s%d", mark, i+1))
+		}
+	}
+	return changed
+}
@@ -0,0 +1,46 @@
+import importlib.util
+import os
+import sys
+import traceback
+import unittest
+
+
+class TapResult(unittest.TestResult):
+    def __init__(self):
+        super().__init__()
+        self.number = 0
+
+    def report(self, status, test, directive="", details=""):
+        self.number += 1
+        print("{} {} - {}{}".format(status, self.number, test.id(), directive))
+        for line in details.splitlines():
+            print("# " + line)
+
+    def addSuccess(self, test):
+        super().addSuccess(test)
+        self.report("ok", test)
+
+    def addFailure(self, test, err):
+        super().addFailure(test, err)
+        self.report("not ok", test, details="".join(traceback.format_exception_only(err[0], err[1])))
+
+    def addError(self, test, err):
+        super().addError(test, err)
+        self.report("not ok", test, details="".join(traceback.format_exception_only(err[0], err[1])))
+
+    def addSkip(self, test, reason):
+        super().addSkip(test, reason)
+        self.report("ok", test, directive=" # SKIP " + reason)
+
+
+path = sys.argv[1]
+sys.path.insert(0, os.getcwd())
+spec = importlib.util.spec_from_file_location("suite", path)
+module = importlib.util.module_from_spec(spec)
+spec.loader.exec_module(module)
+
+suite = unittest.defaultTestLoader.loadTestsFromModule(module)
+print("1..{}".format(suite.countTestCases()))
+result = TapResult()
+suite.run(result)
+sys.exit(0 if result.wasSuccessful() else 1)
@@ -0,0 +1,117 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/trubitsyn/coderator/coderator"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func stress(args []string) int {
+	flags := flag.NewFlagSet("stress", flag.ExitOnError)
+	config := flags.String("config", "serve.yml", "Application config")
+	iterations := flags.Int("n", coderator.DefaultStressIterations, "Number of generated tests")
+	seed := flags.Int64("seed", time.Now().UnixNano()%1000000, "Seed of the first generated test")
+	generatorPath := flags.String("generator", "", "Generator relative to the task file, the one of the first generated test by default")
+	generatorArgs := flags.String("args", "", "Space separated generator arguments")
+	processorName := flags.String("processor", "", "Processor of the solution, the task processor by default")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: coderator stress [-config serve.yml] [-processor name] [-n 100] [-seed N] [-generator path [-args '...']] <task.yml> <solution>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	taskConfig, err := coderator.LoadTaskConfig(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	appConfig, err := coderator.LoadApplicationConfig(*config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if *processorName == "" {
+		*processorName = taskConfig.Task.Processor
+	}
+	if !taskConfig.Task.AllowsProcessor(*processorName) {
+		fmt.Fprintln(os.Stderr, "The task cannot be solved with "+*processorName)
+		return 2
+	}
+	processor := appConfig.FindProcessorByName(*processorName)
+	if processor == nil {
+		fmt.Fprintln(os.Stderr, "Unknown processor "+*processorName)
+		return 2
+	}
+
+	generator := taskConfig.StressGenerator()
+	if *generatorPath != "" {
+		generator = &coderator.Generator{
+			Path:      *generatorPath,
+			Processor: taskConfig.Task.Processor,
+			Args:      strings.Fields(*generatorArgs),
+		}
+	}
+	if generator == nil {
+		fmt.Fprintln(os.Stderr, "The task has no generator, specify one with -generator")
+		return 2
+	}
+
+	solution, err := filepath.Abs(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	options := coderator.StressOptions{Generator: *generator, Iterations: *iterations, Seed: *seed}
+	report, err := coderator.Stress(*taskConfig, *appConfig, *processor, solution, options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	counterexample := report.Counterexample
+	if counterexample == nil {
+		fmt.Printf("No counterexample found in %d tests starting from seed %d\n", report.Iterations, *seed)
+		return 0
+	}
+
+	fmt.Printf("Counterexample found on test %d (seed %d)\n", report.Iterations, counterexample.Seed)
+	fmt.Printf("\nInput:\n%s\n", strings.TrimRight(counterexample.Input, "\n"))
+	fmt.Printf("\nExpected:\n%s\n", counterexample.Expected)
+	if counterexample.Error != "" {
+		fmt.Printf("\nError:\n%s\n", counterexample.Error)
+	} else {
+		fmt.Printf("\nOutput:\n%s\n", counterexample.Output)
+	}
+	return 1
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/trubitsyn/coderator/client"
+	"github.com/trubitsyn/coderator/coderator"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func submit(args []string) int {
+	flags := flag.NewFlagSet("submit", flag.ExitOnError)
+	server := flags.String("server", "http://localhost:8080", "Server URL")
+	token := flags.String("token", os.Getenv("CODERATOR_TOKEN"), "User token, anonymous if empty")
+	task := flags.Uint64("task", 0, "Task id")
+	processor := flags.String("processor", "", "Processor, such as python3.6, detected by the server if empty")
+	wait := flags.Bool("wait", true, "Wait for the results")
+	timeout := flags.Duration("timeout", 10*time.Minute, "Maximum time to wait for the results")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: coderator submit -task <id> [-processor name] [-server url] [-token token] [-timeout 10m] <solution or archive> [files...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *task == 0 || flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	files := make([]coderator.SourceFile, 0, flags.NArg())
+	for _, name := range flags.Args() {
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		if flags.NArg() == 1 || filepath.IsAbs(name) {
+			name = filepath.Base(name)
+		}
+		files = append(files, coderator.SourceFile{Name: filepath.ToSlash(name), Data: data})
+	}
+
+	c := client.New(*server)
+	c.Token = *token
+
+	location, err := c.SubmitFiles(*task, *processor, files)
+	if err != nil {
+		if apiError, ok := err.(*client.APIError); ok && apiError.RetryAfter > 0 {
+			fmt.Fprintf(os.Stderr, "%s (retry in %s)\n", err, apiError.RetryAfter)
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return 1
+	}
+
+	fmt.Println("Submitted to", location)
+	if !*wait {
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	submission, err := c.Wait(ctx, location, time.Second)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	printSubmission(*submission)
+	if submission.Result != coderator.Success {
+		return 1
+	}
+	return 0
+}
+
+func printSubmission(submission coderator.Submission) {
+	if submission.Processor != "" {
+		fmt.Printf("\nSubmission %d (%s): %s\n\n", submission.Id, submission.Processor, submission.Result)
+	} else {
+		fmt.Printf("\nSubmission %d: %s\n\n", submission.Id, submission.Result)
+	}
+	if len(submission.Results) == 0 {
+		return
+	}
+
+	fmt.Printf("%-6s %s\n", "TEST", "VERDICT")
+	passed := 0
+	for i, result := range submission.Results {
+		verdict := "FAILED"
+		if result {
+			verdict = "OK"
+			passed++
+		}
+		fmt.Printf("%-6d %s\n", i+1, verdict)
+	}
+	fmt.Printf("\n%d of %d tests passed\n", passed, len(submission.Results))
+}
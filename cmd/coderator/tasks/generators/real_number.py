@@ -0,0 +1,6 @@
+import random
+import sys
+
+limit, seed = float(sys.argv[1]), int(sys.argv[2])
+random.seed(seed)
+print(random.uniform(-limit, limit))
@@ -0,0 +1,3 @@
+SELECT name, total
+FROM customers JOIN (SELECT customer, SUM(amount) AS total FROM orders GROUP BY customer) ON customers.id = customer
+ORDER BY total DESC, name;
@@ -0,0 +1 @@
+SELECT name, amount FROM customers JOIN orders ON customers.id = customer;
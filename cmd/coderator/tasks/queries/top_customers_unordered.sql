@@ -0,0 +1,3 @@
+SELECT name, SUM(amount) AS total
+FROM customers JOIN orders ON customers.id = customer
+GROUP BY name;
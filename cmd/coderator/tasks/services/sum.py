@@ -0,0 +1,36 @@
+import json
+import os
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+
+class Handler(BaseHTTPRequestHandler):
+    def send(self, status, value):
+        body = json.dumps(value).encode()
+        self.send_response(status)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def do_GET(self):
+        if self.path == "/health":
+            self.send(200, {"status": "ok"})
+        else:
+            self.send(404, {"error": "not found"})
+
+    def do_POST(self):
+        if self.path != "/sum":
+            self.send(404, {"error": "not found"})
+            return
+        try:
+            length = int(self.headers.get("Content-Length", 0))
+            numbers = json.loads(self.rfile.read(length).decode())
+            self.send(200, {"sum": numbers["a"] + numbers["b"]})
+        except (ValueError, KeyError, TypeError):
+            self.send(400, {"error": "bad request"})
+
+    def log_message(self, format, *args):
+        pass
+
+
+HTTPServer((os.environ.get("HOST", "127.0.0.1"), int(os.environ["PORT"])), Handler).serve_forever()
@@ -0,0 +1 @@
+print(abs(float(input())))
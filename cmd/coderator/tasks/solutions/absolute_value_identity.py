@@ -0,0 +1 @@
+print(float(input()))
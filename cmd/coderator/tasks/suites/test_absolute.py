@@ -0,0 +1,14 @@
+import unittest
+
+from solution import absolute
+
+
+class AbsoluteTest(unittest.TestCase):
+    def test_zero(self):
+        self.assertEqual(absolute(0), 0)
+
+    def test_positive(self):
+        self.assertEqual(absolute(1.5), 1.5)
+
+    def test_negative(self):
+        self.assertEqual(absolute(-1.5), 1.5)
@@ -0,0 +1,8 @@
+import unittest
+
+from solution import absolute
+
+
+class AbsoluteTest(unittest.TestCase):
+    def test_negative(self):
+        self.assertEqual(absolute(-2), 2)
@@ -0,0 +1,9 @@
+import sys
+
+try:
+    x = float(input())
+except ValueError:
+    sys.exit("input must be a real number")
+
+if abs(x) > 1000:
+    sys.exit("absolute value of the input must not exceed 1000")
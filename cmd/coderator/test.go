@@ -0,0 +1,226 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/trubitsyn/coderator/coderator"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func test(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	config := flags.String("config", "serve.yml", "Application config")
+	processorName := flags.String("processor", "", "Processor of the solution, detected if empty")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: coderator test [-config serve.yml] [-processor name] <task.yml> <solution>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+
+	taskConfig, err := coderator.LoadTaskConfig(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	appConfig, err := coderator.LoadApplicationConfig(*config)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if taskConfig.Task.Type == coderator.TaskTypeOutput {
+		return testWithJudge(*taskConfig, *appConfig, coderator.LanguageProcessor{}, flags.Arg(1))
+	}
+
+	if *processorName == "" {
+		*processorName = taskConfig.Task.Processor
+		source, err := ioutil.ReadFile(flags.Arg(1))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		detected := coderator.DetectProcessor(taskConfig.Task.AllowedProcessors(*appConfig), flags.Arg(1), source)
+		if detected != nil {
+			*processorName = detected.Name + detected.Version
+		}
+	}
+	if !taskConfig.Task.AllowsProcessor(*processorName) {
+		fmt.Fprintln(os.Stderr, "The task cannot be solved with "+*processorName)
+		return 2
+	}
+	processor := appConfig.FindProcessorByName(*processorName)
+	if processor == nil {
+		fmt.Fprintln(os.Stderr, "Unknown processor "+*processorName)
+		return 2
+	}
+
+	if taskConfig.Task.Type != "" && taskConfig.Task.Type != coderator.TaskTypeIO {
+		return testWithJudge(*taskConfig, *appConfig, *processor, flags.Arg(1))
+	}
+
+	solution, cleanup, err := taskConfig.Task.PrepareSolution(*processor, flags.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer cleanup()
+	if solution, err = filepath.Abs(solution); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	tests, err := taskConfig.MaterializeTests(*appConfig)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	fmt.Printf("%s (%s)\n\n", taskConfig.Task.Title, *processorName)
+	fmt.Printf("%-6s %-8s %s\n", "TEST", "VERDICT", "INPUT")
+
+	tester := taskConfig.Task.Tester(*processor)
+	passed := 0
+	for i, test := range tests {
+		result := tester.Run(*processor, solution, test)
+		verdict := "OK"
+		if result.Error == coderator.ErrTimeLimitExceeded {
+			verdict = "TIME"
+		} else if result.Error != nil {
+			verdict = "ERROR"
+		} else if !result.Successful {
+			verdict = "FAILED"
+		}
+		fmt.Printf("%-6d %-8s %s\n", i+1, verdict, firstLine(test.Input))
+
+		if result.Error != nil {
+			fmt.Printf("       %s\n", result.Error)
+		} else if result.Successful {
+			passed++
+		} else {
+			printDiff(test.Output, result.Output)
+		}
+	}
+
+	fmt.Printf("\n%d of %d tests passed\n", passed, len(tests))
+	if passed != len(tests) {
+		return 1
+	}
+	return 0
+}
+
+func firstLine(text string) string {
+	lines := strings.SplitN(strings.TrimRight(text, "\n"), "\n", 2)
+	if len(lines) > 1 {
+		return lines[0] + " ..."
+	}
+	return lines[0]
+}
+
+// printDiff shows the lines of the output that differ from the expected one.
+func printDiff(expected string, actual string) {
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		var want, got string
+		if i < len(expectedLines) {
+			want = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			got = actualLines[i]
+		}
+		if want == got {
+			continue
+		}
+
+		fmt.Printf("       line %d:\n", i+1)
+		if i < len(expectedLines) {
+			fmt.Printf("       - %s\n", want)
+		}
+		if i < len(actualLines) {
+			fmt.Printf("       + %s\n", got)
+		}
+	}
+}
+
+// testWithJudge runs the solution of a task whose tests are not inputs and
+// outputs, printing the name of every test.
+func testWithJudge(taskConfig coderator.TaskConfig, appConfig coderator.ApplicationConfig, processor coderator.LanguageProcessor, solution string) int {
+	judge := coderator.FindJudgeByTask(taskConfig.Task)
+	if judge == nil {
+		fmt.Fprintln(os.Stderr, "Unknown task type "+taskConfig.Task.Type)
+		return 2
+	}
+
+	if name := processor.Name + processor.Version; name != "" {
+		fmt.Printf("%s (%s, %s)\n\n", taskConfig.Task.Title, taskConfig.Task.Type, name)
+	} else {
+		fmt.Printf("%s (%s)\n\n", taskConfig.Task.Title, taskConfig.Task.Type)
+	}
+	results, err := judge.Run(taskConfig, appConfig, processor, solution)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	counted := "tests passed"
+	if taskConfig.Task.Type == coderator.TaskTypeMutation {
+		counted = "mutants killed"
+	}
+
+	fmt.Printf("%-6s %-8s %s\n", "TEST", "VERDICT", "NAME")
+	passed := 0
+	for i, result := range results {
+		verdict := "FAILED"
+		if result.Error == coderator.ErrTimeLimitExceeded {
+			verdict = "TIME"
+		} else if result.Error != nil {
+			verdict = "ERROR"
+		} else if result.Successful {
+			verdict = "OK"
+			passed++
+		}
+		fmt.Printf("%-6d %-8s %s\n", i+1, verdict, result.Name)
+		if result.Error != nil {
+			fmt.Printf("       %s\n", result.Error)
+		}
+		if !result.Successful && result.Output != "" {
+			for _, line := range strings.Split(result.Output, "\n") {
+				fmt.Printf("       %s\n", line)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d of %d %s\n", passed, len(results), counted)
+	if passed != len(results) {
+		return 1
+	}
+	return 0
+}
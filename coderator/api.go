@@ -21,12 +21,20 @@ package coderator
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
+	"io"
+	"io/ioutil"
 	"log"
+	"mime"
+	"mime/multipart"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -35,6 +43,27 @@ const (
 	ErrorNoTests          = "No tests found with the specified task id"
 	ErrorJobDoesNotExist  = "The specified job does not exist"
 	ErrorNoResults        = "No results for the specified task"
+	ErrorRateLimited      = "Too many requests, try again later"
+	ErrorQuotaExhausted   = "No attempts left for the specified task"
+	ErrorCooldown         = "Too early since the previous attempt"
+	ErrorNoContests       = "No contests found"
+	ErrorContestNotFound  = "The specified contest does not exist"
+	ErrorContestOver      = "The specified contest is over"
+	ErrorNotRegistered    = "You are not registered for the specified contest"
+	ErrorNotStarted       = "The contest of the specified task has not started"
+	ErrorNotAdmin         = "Administrator token required"
+	ErrorNoClarification  = "The specified clarification does not exist"
+	ErrorEmptyMessage     = "The message must not be empty"
+	ErrorNoStreaming      = "Streaming is not supported"
+	ErrorHackingClosed    = "Hacking is not open for the specified submission"
+	ErrorNotAccepted      = "Only accepted submissions can be hacked"
+	ErrorOwnSubmission    = "You cannot hack your own submission"
+	ErrorNoInput          = "No hack input provided"
+	ErrorNoSource         = "No source provided"
+	ErrorNoProcessors     = "No processors found"
+	ErrorProcessorDenied  = "The specified processor is not allowed for the task"
+	ErrorNoEntryPoint     = "The entry point of the submission is missing"
+	ErrorNoTemplate       = "No template for the specified task and language"
 )
 
 const (
@@ -44,8 +73,51 @@ const (
 	PathSolve   = "/tasks/{id}/solve"
 	PathQueue   = "/queue/{id}"
 	PathResults = "/tasks/{id}/results"
+
+	PathSubmission = "/submissions/{id}"
+
+	PathContests   = "/contests"
+	PathContest    = "/contests/{id}"
+	PathRegister   = "/contests/{id}/register"
+	PathScoreboard = "/contests/{id}/scoreboard"
+
+	PathClarifications = "/contests/{id}/clarifications"
+	PathAnswer         = "/contests/{id}/clarifications/{clarification}/answer"
+	PathAnnouncements  = "/contests/{id}/announcements"
+	PathEvents         = "/contests/{id}/events"
+	PathHack           = "/submissions/{id}/hack"
+	PathHacks          = "/contests/{id}/hacks"
+
+	PathRejudgeSubmission = "/submissions/{id}/rejudge"
+	PathRejudgeTask       = "/tasks/{id}/rejudge"
+	PathRejudgeContest    = "/contests/{id}/rejudge"
+
+	PathStress = "/tasks/{id}/stress"
+
+	PathProcessors = "/processors"
+	PathTemplate   = "/tasks/{id}/template"
+)
+
+const (
+	HeaderRateLimit          = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderQuotaLimit         = "X-Quota-Limit"
+	HeaderQuotaRemaining     = "X-Quota-Remaining"
 )
 
+// Template is the starter code of a task for a processor.
+type Template struct {
+	Processor string
+	Code      string
+}
+
+// TaskDetails is a task along with its limits for the processors it can be
+// solved with.
+type TaskDetails struct {
+	Task
+	Limits map[string]ResourceLimits
+}
+
 type Error struct {
 	Error string `json:"error"`
 }
@@ -55,12 +127,34 @@ type Repository interface {
 	FindTaskById(id uint64) (*Task, error)
 	AddTask(task Task)
 	FindTestsByTaskId(taskId uint64) ([]Test, error)
+	AddTest(taskId uint64, test Test) error
+}
+
+type ContestRepository interface {
+	AllContests() ([]Contest, error)
+	FindContestById(id uint64) (*Contest, error)
 }
 
 var database Repository
+var contests ContestRepository
+var application *ApplicationConfig
+var limiter *RateLimiter
 
 func Serve(repository Repository, port int) {
 	database = repository
+
+	config := Config{}
+	appConfig, err := config.ApplicationConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	application = appConfig
+	if err = CleanWorkDirs(application.WorkDirRoot()); err != nil {
+		log.Fatal(err)
+	}
+	contests = config
+	limiter = NewRateLimiter(application.Limits.Rate)
+
 	router := mux.NewRouter().StrictSlash(true)
 	router.HandleFunc(PathTasks, tasksEndpoint).Methods("GET")
 	router.HandleFunc(PathTask, taskEndpoint).Methods("GET")
@@ -68,6 +162,25 @@ func Serve(repository Repository, port int) {
 	router.HandleFunc(PathSolve, taskSolveEndpoint).Methods("POST")
 	router.HandleFunc(PathQueue, taskSolveQueueEndpoint).Methods("GET")
 	router.HandleFunc(PathResults, taskSolveResultsEndpoint).Methods("GET")
+	router.HandleFunc(PathSubmission, submissionEndpoint).Methods("GET")
+	router.HandleFunc(PathContests, contestsEndpoint).Methods("GET")
+	router.HandleFunc(PathContest, contestEndpoint).Methods("GET")
+	router.HandleFunc(PathRegister, contestRegisterEndpoint).Methods("POST")
+	router.HandleFunc(PathScoreboard, contestScoreboardEndpoint).Methods("GET")
+	router.HandleFunc(PathClarifications, clarificationsEndpoint).Methods("GET")
+	router.HandleFunc(PathClarifications, askClarificationEndpoint).Methods("POST")
+	router.HandleFunc(PathAnswer, answerClarificationEndpoint).Methods("POST")
+	router.HandleFunc(PathAnnouncements, announcementsEndpoint).Methods("GET")
+	router.HandleFunc(PathAnnouncements, announceEndpoint).Methods("POST")
+	router.HandleFunc(PathEvents, contestEventsEndpoint).Methods("GET")
+	router.HandleFunc(PathHack, hackEndpoint).Methods("POST")
+	router.HandleFunc(PathHacks, hacksEndpoint).Methods("GET")
+	router.HandleFunc(PathRejudgeSubmission, rejudgeSubmissionEndpoint).Methods("POST")
+	router.HandleFunc(PathRejudgeTask, rejudgeTaskEndpoint).Methods("POST")
+	router.HandleFunc(PathRejudgeContest, rejudgeContestEndpoint).Methods("POST")
+	router.HandleFunc(PathStress, stressEndpoint).Methods("POST")
+	router.HandleFunc(PathProcessors, processorsEndpoint).Methods("GET")
+	router.HandleFunc(PathTemplate, taskTemplateEndpoint).Methods("GET")
 
 	log.Fatal(http.ListenAndServe(":"+strconv.Itoa(port), router))
 }
@@ -99,7 +212,64 @@ func taskEndpoint(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(Error{ErrorTaskDoesNotExist})
 		return
 	}
-	json.NewEncoder(w).Encode(task)
+
+	details := TaskDetails{Task: *task, Limits: make(map[string]ResourceLimits)}
+	for _, processor := range task.AllowedProcessors(*application) {
+		details.Limits[processor.Name+processor.Version] = task.Limits(processor)
+	}
+	json.NewEncoder(w).Encode(details)
+}
+
+func taskTemplateEndpoint(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idParam := vars["id"]
+
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorTaskDoesNotExist})
+		return
+	}
+
+	task, err := database.FindTaskById(id)
+	if err != nil || task == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorTaskDoesNotExist})
+		return
+	}
+
+	name := r.URL.Query().Get("lang")
+	if name == "" {
+		name = task.Processor
+	}
+	processor := application.FindProcessorByName(name)
+	if !task.AllowsProcessor(name) || processor == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{ErrorProcessorDenied})
+		return
+	}
+
+	code, err := task.Starter(*processor)
+	if err != nil {
+		fmt.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if code == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorNoTemplate})
+		return
+	}
+	json.NewEncoder(w).Encode(Template{Processor: name, Code: code})
+}
+
+func processorsEndpoint(w http.ResponseWriter, r *http.Request) {
+	if len(application.Processors) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorNoProcessors})
+		return
+	}
+	json.NewEncoder(w).Encode(application.Processors)
 }
 
 func taskTestsEndpoint(w http.ResponseWriter, r *http.Request) {
@@ -140,9 +310,26 @@ func taskSolveEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if IsVerificationQueued(*task) {
-		w.Header().Set("Location", strings.Replace(PathQueue, "{id}", idParam, 1))
-		w.WriteHeader(http.StatusTooManyRequests)
+	user := clientId(r)
+	if !allowRequest(w, r, user) {
+		return
+	}
+
+	denial, err := contestDenial(task.Id, user, time.Now())
+	if err != nil {
+		fmt.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if denial != "" {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Error{denial})
+		return
+	}
+
+	quota := EffectiveQuota(application.Limits.Quota, *task)
+	if remaining, wait, allowed := CheckQuota(quota, *task, user, time.Now()); !allowed {
+		rejectQuota(w, quota, remaining, wait)
 		return
 	}
 
@@ -153,26 +340,128 @@ func taskSolveEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	source, _, err := r.FormFile("source")
+	files, err := readSourceFiles(r.MultipartForm.File["source"])
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(Error{"Could not parse form file"})
+		json.NewEncoder(w).Encode(Error{err.Error()})
 		return
 	}
-	defer source.Close()
 
-	path, err := SaveSolution(*task, source)
-	if err != nil {
-		fmt.Println(err)
-		w.WriteHeader(http.StatusInternalServerError)
+	// Output-only submissions are outputs rather than programs, judged
+	// without a processor.
+	processor, entry, detected := "", "", false
+	if task.Type != TaskTypeOutput {
+		processor = r.FormValue("processor")
+		if processor == "" {
+			processor = task.Processor
+			for _, file := range files {
+				found := DetectProcessor(task.AllowedProcessors(*application), file.Name, file.Data)
+				if found != nil {
+					processor = found.Name + found.Version
+					detected = true
+					break
+				}
+			}
+		}
+		languageProcessor := application.FindProcessorByName(processor)
+		if !task.AllowsProcessor(processor) || languageProcessor == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Error{ErrorProcessorDenied})
+			return
+		}
+
+		entry = task.EntryPoint(*languageProcessor)
+		if len(files) == 1 {
+			if entry == "" {
+				entry = files[0].Name
+			}
+			files[0].Name = entry
+		}
+		if FindSourceFileByName(files, entry) == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Error{ErrorNoEntryPoint})
+			return
+		}
+	}
+
+	// The quota is checked again together with the insert, as concurrent
+	// submissions may have used it up since the check above.
+	created, remaining, wait := SubmitWithinQuota(quota, *task, user, processor, files, entry, time.Now())
+	if created == nil {
+		rejectQuota(w, quota, remaining, wait)
 		return
 	}
-	go VerifyTaskSolution(*task, path)
+	submission := *created
+	if detected {
+		submission.Detected = true
+		UpdateSubmission(submission)
+	}
+	if quota.Attempts > 0 {
+		w.Header().Set(HeaderQuotaLimit, strconv.Itoa(quota.Attempts))
+		w.Header().Set(HeaderQuotaRemaining, strconv.Itoa(remaining-1))
+	}
+
+	Queue(submission)
+	go VerifyTaskSolution(*task, submission)
 
-	w.Header().Set("Location", strings.Replace(PathQueue, "{id}", idParam, 1))
+	w.Header().Set("Location", strings.Replace(PathQueue, "{id}", fmt.Sprint(submission.Id), 1))
 	w.WriteHeader(http.StatusAccepted)
 }
 
+// readSourceFiles reads the files uploaded as a submission. A single archive
+// is extracted, other files are kept under their names.
+func readSourceFiles(headers []*multipart.FileHeader) ([]SourceFile, error) {
+	if len(headers) == 0 {
+		return nil, errors.New(ErrorNoSource)
+	}
+	if len(headers) > MaxSubmissionFiles {
+		return nil, ErrTooManyFiles
+	}
+
+	files := make([]SourceFile, 0, len(headers))
+	size := 0
+	for _, header := range headers {
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(io.LimitReader(file, MaxSubmissionSize+1))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		size += len(data)
+		if size > MaxSubmissionSize {
+			return nil, ErrSubmissionTooLarge
+		}
+
+		if len(headers) == 1 && IsArchive(header.Filename) {
+			return ExtractArchive(header.Filename, data)
+		}
+
+		name := uploadedName(header)
+		if name == "" {
+			name = "source"
+		}
+		name, err = CleanSourceName(name)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, SourceFile{name, data})
+	}
+	return files, nil
+}
+
+// uploadedName returns the file name as sent by the client, including the
+// directories stripped from FileHeader.Filename.
+func uploadedName(header *multipart.FileHeader) string {
+	_, params, err := mime.ParseMediaType(header.Header.Get("Content-Disposition"))
+	if err != nil || params["filename"] == "" {
+		return header.Filename
+	}
+	return params["filename"]
+}
+
 func taskSolveQueueEndpoint(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idParam := vars["id"]
@@ -184,23 +473,21 @@ func taskSolveQueueEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task, err := database.FindTaskById(id)
-	if err != nil || task == nil {
+	submission := FindSubmissionById(id)
+	if submission == nil {
 		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(Error{ErrorTaskDoesNotExist})
+		json.NewEncoder(w).Encode(Error{ErrorJobDoesNotExist})
 		return
 	}
 
-	if HasVerificationCompleted(*task) {
-		w.Header().Set("Location", strings.Replace(PathResults, "{id}", idParam, 1))
+	if HasVerificationCompleted(*submission) {
+		w.Header().Set("Location", strings.Replace(PathSubmission, "{id}", idParam, 1))
 		w.WriteHeader(http.StatusSeeOther)
 	} else {
-		if !IsVerificationQueued(*task) {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(Error{ErrorJobDoesNotExist})
-		} else {
-			w.WriteHeader(http.StatusProcessing)
-		}
+		// 102 Processing is an informational status, net/http follows it
+		// with 200 OK, so report the pending job as accepted instead.
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusAccepted)
 	}
 }
 
@@ -222,7 +509,15 @@ func taskSolveResultsEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	results := GetVerificationResults(*task)
+	var results []bool
+	submissions := FindSubmissionsByTaskAndUser(task.Id, clientId(r))
+	for i := len(submissions) - 1; i >= 0; i-- {
+		if HasVerificationCompleted(submissions[i]) {
+			results = GetVerificationResults(submissions[i])
+			break
+		}
+	}
+
 	if results == nil {
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(Error{ErrorNoResults})
@@ -230,3 +525,618 @@ func taskSolveResultsEndpoint(w http.ResponseWriter, r *http.Request) {
 	}
 	json.NewEncoder(w).Encode(results)
 }
+
+func submissionEndpoint(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idParam := vars["id"]
+
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorJobDoesNotExist})
+		return
+	}
+
+	submission := FindSubmissionById(id)
+	if submission == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorJobDoesNotExist})
+		return
+	}
+	json.NewEncoder(w).Encode(submission)
+}
+
+func contestsEndpoint(w http.ResponseWriter, r *http.Request) {
+	all, err := contests.AllContests()
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorNoContests})
+		return
+	}
+	json.NewEncoder(w).Encode(all)
+}
+
+func contestEndpoint(w http.ResponseWriter, r *http.Request) {
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+	json.NewEncoder(w).Encode(contest)
+}
+
+func contestRegisterEndpoint(w http.ResponseWriter, r *http.Request) {
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+
+	if !time.Now().Before(contest.End) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Error{ErrorContestOver})
+		return
+	}
+
+	Register(*contest, clientId(r))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func contestScoreboardEndpoint(w http.ResponseWriter, r *http.Request) {
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+
+	now := time.Now()
+	visible := now
+	frozen := contest.IsFrozen(now) && !isAdmin(r)
+	if frozen {
+		visible = contest.End.Add(-contest.Freeze)
+	}
+
+	scoreboard := ComputeScoreboard(*contest, Participants(*contest), AllSubmissions(), visible)
+	scoreboard.Frozen = frozen
+	json.NewEncoder(w).Encode(scoreboard)
+}
+
+func clarificationsEndpoint(w http.ResponseWriter, r *http.Request) {
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+
+	user := clientId(r)
+	if isAdmin(r) {
+		user = ""
+	}
+	json.NewEncoder(w).Encode(FindClarifications(*contest, user))
+}
+
+func askClarificationEndpoint(w http.ResponseWriter, r *http.Request) {
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+
+	user := clientId(r)
+	if !IsRegistered(*contest, user) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Error{ErrorNotRegistered})
+		return
+	}
+
+	var taskId uint64
+	if taskParam := r.FormValue("task"); taskParam != "" {
+		id, err := strconv.ParseUint(taskParam, 10, 64)
+		if err != nil || !contest.HasTask(id) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Error{ErrorTaskDoesNotExist})
+			return
+		}
+		taskId = id
+	}
+
+	question := strings.TrimSpace(r.FormValue("question"))
+	if question == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{ErrorEmptyMessage})
+		return
+	}
+
+	clarification := AskClarification(*contest, taskId, user, question)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(clarification)
+}
+
+func answerClarificationEndpoint(w http.ResponseWriter, r *http.Request) {
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+
+	if !isAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Error{ErrorNotAdmin})
+		return
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["clarification"], 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorNoClarification})
+		return
+	}
+
+	clarification := FindClarificationById(id)
+	if clarification == nil || clarification.ContestId != contest.Id {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorNoClarification})
+		return
+	}
+
+	answer := strings.TrimSpace(r.FormValue("answer"))
+	if answer == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{ErrorEmptyMessage})
+		return
+	}
+
+	public, _ := strconv.ParseBool(r.FormValue("public"))
+	json.NewEncoder(w).Encode(AnswerClarification(id, answer, public))
+}
+
+func announcementsEndpoint(w http.ResponseWriter, r *http.Request) {
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+	json.NewEncoder(w).Encode(FindAnnouncements(*contest))
+}
+
+func announceEndpoint(w http.ResponseWriter, r *http.Request) {
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+
+	if !isAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Error{ErrorNotAdmin})
+		return
+	}
+
+	var taskId uint64
+	if taskParam := r.FormValue("task"); taskParam != "" {
+		id, err := strconv.ParseUint(taskParam, 10, 64)
+		if err != nil || !contest.HasTask(id) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Error{ErrorTaskDoesNotExist})
+			return
+		}
+		taskId = id
+	}
+
+	text := strings.TrimSpace(r.FormValue("text"))
+	if text == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{ErrorEmptyMessage})
+		return
+	}
+
+	announcement := Announce(*contest, taskId, text)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(announcement)
+}
+
+// contestEventsEndpoint streams the contest events as server-sent events
+// until the client disconnects.
+func contestEventsEndpoint(w http.ResponseWriter, r *http.Request) {
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(Error{ErrorNoStreaming})
+		return
+	}
+
+	user := clientId(r)
+	if isAdmin(r) {
+		user = ""
+	}
+	stream, cancel := events.Subscribe(contest.Id, user)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-stream:
+			data, err := json.Marshal(event.Data)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+func hackEndpoint(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	idParam := vars["id"]
+
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorJobDoesNotExist})
+		return
+	}
+
+	target := FindSubmissionById(id)
+	if target == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorJobDoesNotExist})
+		return
+	}
+
+	hacker := clientId(r)
+	if !allowRequest(w, r, hacker) {
+		return
+	}
+
+	if target.Result != Success {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{ErrorNotAccepted})
+		return
+	}
+	if target.User == hacker {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{ErrorOwnSubmission})
+		return
+	}
+
+	contest := findHackingContest(*target, hacker)
+	if contest == nil {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Error{ErrorHackingClosed})
+		return
+	}
+
+	input := r.FormValue("input")
+	if file, _, err := r.FormFile("input"); err == nil {
+		data, err := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(Error{"Could not parse form file"})
+			return
+		}
+		input = string(data)
+	}
+	if input == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{ErrorNoInput})
+		return
+	}
+
+	hack, err := RunHack(*contest, *target, hacker, input)
+	if err != nil {
+		fmt.Println(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hack)
+}
+
+func hacksEndpoint(w http.ResponseWriter, r *http.Request) {
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+	json.NewEncoder(w).Encode(FindHacksByContest(*contest))
+}
+
+// contestDenial returns why the user may not submit a solution of the task
+// now, or an empty string. The tasks of a running contest only accept
+// solutions from the users registered for it, and the tasks of a contest
+// not started yet accept none. Once a contest is over its tasks are open to
+// everyone, the solutions being left off the scoreboard.
+func contestDenial(taskId uint64, user string, now time.Time) (string, error) {
+	all, err := contests.AllContests()
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	denial := ""
+	for _, contest := range all {
+		if !contest.HasTask(taskId) {
+			continue
+		}
+		if !now.Before(contest.End) {
+			return "", nil
+		}
+		if !contest.IsRunning(now) {
+			if denial == "" {
+				denial = ErrorNotStarted
+			}
+			continue
+		}
+		if IsRegistered(contest, user) {
+			return "", nil
+		}
+		denial = ErrorNotRegistered
+	}
+	return denial, nil
+}
+
+// findHackingContest returns the contest in which both the hacker and the
+// author of the target submission take part and hacking is open.
+func findHackingContest(target Submission, hacker string) *Contest {
+	all, err := contests.AllContests()
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+
+	now := time.Now()
+	for _, contest := range all {
+		if contest.HasTask(target.TaskId) && contest.IsRunning(target.Time) && contest.IsHackingOpen(now) &&
+			IsRegistered(contest, hacker) && IsRegistered(contest, target.User) {
+			return &contest
+		}
+	}
+	return nil
+}
+
+func rejudgeSubmissionEndpoint(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Error{ErrorNotAdmin})
+		return
+	}
+
+	vars := mux.Vars(r)
+	idParam := vars["id"]
+
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorJobDoesNotExist})
+		return
+	}
+
+	submission := FindSubmissionById(id)
+	if submission == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorJobDoesNotExist})
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(RejudgeAll([]Submission{*submission}))
+}
+
+func rejudgeTaskEndpoint(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Error{ErrorNotAdmin})
+		return
+	}
+
+	vars := mux.Vars(r)
+	idParam := vars["id"]
+
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorTaskDoesNotExist})
+		return
+	}
+
+	task, err := database.FindTaskById(id)
+	if err != nil || task == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorTaskDoesNotExist})
+		return
+	}
+
+	found := make([]Submission, 0)
+	for _, submission := range AllSubmissions() {
+		if submission.TaskId == task.Id {
+			found = append(found, submission)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(RejudgeAll(found))
+}
+
+func rejudgeContestEndpoint(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Error{ErrorNotAdmin})
+		return
+	}
+
+	contest := findContest(w, r)
+	if contest == nil {
+		return
+	}
+
+	found := make([]Submission, 0)
+	for _, submission := range AllSubmissions() {
+		if contest.HasTask(submission.TaskId) && contest.IsRunning(submission.Time) {
+			found = append(found, submission)
+		}
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(RejudgeAll(found))
+}
+
+func findContest(w http.ResponseWriter, r *http.Request) *Contest {
+	vars := mux.Vars(r)
+	idParam := vars["id"]
+
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorContestNotFound})
+		return nil
+	}
+
+	contest, err := contests.FindContestById(id)
+	if err != nil || contest == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorContestNotFound})
+		return nil
+	}
+	return contest
+}
+
+func isAdmin(r *http.Request) bool {
+	token := application.Admin.Token
+	return token != "" && r.Header.Get("Authorization") == "Bearer "+token
+}
+
+// clientId identifies the user by the bearer token of a configured user,
+// falling back to the remote address for anonymous clients.
+func clientId(r *http.Request) string {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if user := application.FindUserByToken(token); user != nil {
+		return user.Name
+	}
+	return remoteHost(r)
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// allowRequest applies the rate limit both to the remote address and to the
+// user so that neither switching users nor addresses bypasses it.
+func allowRequest(w http.ResponseWriter, r *http.Request, user string) bool {
+	limits := application.Limits
+	if limits.Rate.Requests <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	remaining, wait, allowed := limiter.Allow("ip:"+remoteHost(r), now)
+	if allowed && user != remoteHost(r) {
+		var userRemaining int
+		userRemaining, wait, allowed = limiter.Allow("user:"+user, now)
+		if userRemaining < remaining {
+			remaining = userRemaining
+		}
+	}
+
+	w.Header().Set(HeaderRateLimit, strconv.Itoa(limits.Rate.Requests))
+	w.Header().Set(HeaderRateLimitRemaining, strconv.Itoa(remaining))
+	if !allowed {
+		setRetryAfter(w, wait)
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(Error{ErrorRateLimited})
+	}
+	return allowed
+}
+
+// rejectQuota responds that the quota of the user for the task is exhausted
+// or, when only the cooldown has not passed yet, when to retry.
+func rejectQuota(w http.ResponseWriter, quota Quota, remaining int, wait time.Duration) {
+	if quota.Attempts > 0 {
+		w.Header().Set(HeaderQuotaLimit, strconv.Itoa(quota.Attempts))
+		w.Header().Set(HeaderQuotaRemaining, strconv.Itoa(remaining))
+	}
+	if remaining == 0 {
+		w.WriteHeader(http.StatusTooManyRequests)
+		json.NewEncoder(w).Encode(Error{ErrorQuotaExhausted})
+		return
+	}
+	setRetryAfter(w, wait)
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(Error{ErrorCooldown})
+}
+
+func setRetryAfter(w http.ResponseWriter, wait time.Duration) {
+	seconds := int((wait + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+func stressEndpoint(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(Error{ErrorNotAdmin})
+		return
+	}
+
+	vars := mux.Vars(r)
+	idParam := vars["id"]
+
+	id, err := strconv.ParseUint(idParam, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorTaskDoesNotExist})
+		return
+	}
+
+	task, err := database.FindTaskById(id)
+	if err != nil || task == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Error{ErrorTaskDoesNotExist})
+		return
+	}
+
+	file, _, err := r.FormFile("source")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{ErrorNoSource})
+		return
+	}
+	source, err := ioutil.ReadAll(file)
+	file.Close()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(Error{"Could not parse form file"})
+		return
+	}
+
+	iterations := DefaultStressIterations
+	if value, err := strconv.Atoi(r.FormValue("iterations")); err == nil && value > 0 {
+		iterations = value
+	}
+	if iterations > MaxStressIterations {
+		iterations = MaxStressIterations
+	}
+
+	seed := time.Now().UnixNano() % 1000000
+	if value, err := strconv.ParseInt(r.FormValue("seed"), 10, 64); err == nil {
+		seed = value
+	}
+
+	report, err := StressTask(task.Id, source, r.FormValue("processor"), iterations, seed)
+	if err != nil {
+		fmt.Println(err)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(Error{err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	MaxSubmissionSize  = 8 << 20
+	MaxSubmissionFiles = 256
+)
+
+var (
+	ErrSubmissionTooLarge = errors.New("The submission is too large")
+	ErrTooManyFiles       = errors.New("The submission has too many files")
+)
+
+// SourceFile is a file of a multi-file submission. Name is a slash separated
+// path relative to the working directory of the submission.
+type SourceFile struct {
+	Name string
+	Data []byte
+}
+
+// IsArchive reports whether the file is a zip or tar archive judging by its
+// name.
+func IsArchive(filename string) bool {
+	name := strings.ToLower(filename)
+	for _, extension := range []string{".zip", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(name, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractArchive reads the regular files of a zip or tar archive, possibly
+// gzipped. Names escaping the archive root are rejected, and so are archives
+// exceeding MaxSubmissionSize or MaxSubmissionFiles once unpacked.
+func ExtractArchive(filename string, data []byte) ([]SourceFile, error) {
+	if strings.HasSuffix(strings.ToLower(filename), ".zip") {
+		return extractZip(data)
+	}
+
+	var reader io.Reader = bytes.NewReader(data)
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+		reader = gzipReader
+	}
+	return extractTar(reader)
+}
+
+func extractZip(data []byte) ([]SourceFile, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]SourceFile, 0)
+	size := int64(0)
+	for _, entry := range archive.File {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		file, err := entry.Open()
+		if err != nil {
+			return nil, err
+		}
+		files, size, err = appendSourceFile(files, size, entry.Name, file)
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func extractTar(reader io.Reader) ([]SourceFile, error) {
+	archive := tar.NewReader(reader)
+
+	files := make([]SourceFile, 0)
+	size := int64(0)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+		files, size, err = appendSourceFile(files, size, header.Name, archive)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func appendSourceFile(files []SourceFile, size int64, name string, reader io.Reader) ([]SourceFile, int64, error) {
+	if len(files) >= MaxSubmissionFiles {
+		return nil, 0, ErrTooManyFiles
+	}
+
+	cleaned, err := CleanSourceName(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(reader, MaxSubmissionSize-size+1))
+	if err != nil {
+		return nil, 0, err
+	}
+	size += int64(len(data))
+	if size > MaxSubmissionSize {
+		return nil, 0, ErrSubmissionTooLarge
+	}
+	return append(files, SourceFile{cleaned, data}), size, nil
+}
+
+// CleanSourceName checks that the name stays inside the working directory
+// and returns it in the canonical form.
+func CleanSourceName(name string) (string, error) {
+	name = strings.Replace(name, "\\", "/", -1)
+	cleaned := path.Clean(name)
+	if name == "" || path.IsAbs(name) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("Invalid file name %q", name)
+	}
+	return cleaned, nil
+}
+
+// FindSourceFileByName returns the file of the given name, nil if there is
+// none.
+func FindSourceFileByName(files []SourceFile, name string) *SourceFile {
+	for _, file := range files {
+		if file.Name == name {
+			return &file
+		}
+	}
+	return nil
+}
+
+// WriteSourceFiles writes the files into the directory and returns the path
+// of the entry point.
+func WriteSourceFiles(dir string, files []SourceFile, entry string) (string, error) {
+	for _, file := range files {
+		target := filepath.Join(dir, filepath.FromSlash(file.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(target, file.Data, 0600); err != nil {
+			return "", err
+		}
+	}
+	return filepath.Abs(filepath.Join(dir, filepath.FromSlash(entry)))
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultCalibrationRuns   = 5
+	DefaultCalibrationMargin = 3
+	TimeLimitPrecision       = 100 * time.Millisecond
+)
+
+// CalibrationConfig controls how time limits are proposed. How much slower
+// a processor is than a compiled language is told by its time multiplier.
+type CalibrationConfig struct {
+	Runs   int
+	Margin float64
+}
+
+func (config CalibrationConfig) runs() int {
+	if config.Runs <= 0 {
+		return DefaultCalibrationRuns
+	}
+	return config.Runs
+}
+
+func (config CalibrationConfig) margin() float64 {
+	if config.Margin <= 0 {
+		return DefaultCalibrationMargin
+	}
+	return config.Margin
+}
+
+// Measurement is the running time of the slowest run of a solution on its
+// slowest test.
+type Measurement struct {
+	Solution Solution
+	Time     time.Duration
+}
+
+// CalibrateTimeLimits runs every accepted solution of the task on all tests
+// several times and proposes a time limit for every processor the task
+// allows. The slowest solution time is scaled down by the multiplier
+// of its processor, then up by the multiplier of the proposed processor and
+// the safety margin, and rounded up to TimeLimitPrecision. Only the tasks
+// judged on the output of the solution, io and function tasks, are
+// calibrated.
+func CalibrateTimeLimits(taskConfig TaskConfig, appConfig ApplicationConfig) (map[string]time.Duration, []Measurement, error) {
+	calibration := appConfig.Calibration
+	if taskType := taskConfig.Task.Type; taskType != "" && taskType != TaskTypeIO {
+		return nil, nil, errors.New("Time limits of " + taskType + " tasks cannot be calibrated, only io and function tasks are supported")
+	}
+
+	tests, err := taskConfig.MaterializeTests(appConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	measurements := make([]Measurement, 0)
+	base := 0.0
+	tester := Tester{Stdin: taskConfig.Task.ReadsStdin()}
+	for _, solution := range taskConfig.Solutions {
+		if solution.ExpectedVerdict() != VerdictAccepted {
+			continue
+		}
+
+		processor := appConfig.FindProcessorByName(solution.Processor)
+		if processor == nil {
+			return nil, nil, errors.New("Unknown processor " + solution.Processor)
+		}
+
+		path, cleanup, err := taskConfig.Task.PrepareSolution(*processor, taskConfig.ProgramPath(solution.Program()))
+		if err != nil {
+			return nil, nil, err
+		}
+		measurement, err := measure(tester, *processor, path, solution, tests, calibration.runs())
+		cleanup()
+		if err != nil {
+			return nil, nil, err
+		}
+		measurements = append(measurements, measurement)
+
+		normalized := float64(measurement.Time) / processor.Multiplier()
+		if normalized > base {
+			base = normalized
+		}
+	}
+	if len(measurements) == 0 {
+		return nil, nil, errors.New("The task has no accepted solutions")
+	}
+
+	limits := make(map[string]time.Duration)
+	for _, processor := range taskConfig.Task.AllowedProcessors(appConfig) {
+		name := processor.Name + processor.Version
+		limit := time.Duration(base * processor.Multiplier() * calibration.margin())
+		limits[name] = (limit + TimeLimitPrecision - 1) / TimeLimitPrecision * TimeLimitPrecision
+	}
+	return limits, measurements, nil
+}
+
+// measure runs the prepared solution on every test the given number of
+// times and returns its slowest run.
+func measure(tester Tester, processor LanguageProcessor, path string, solution Solution, tests []Test, runs int) (Measurement, error) {
+	measurement := Measurement{Solution: solution}
+	for i, test := range tests {
+		for run := 0; run < runs; run++ {
+			start := time.Now()
+			result := tester.Run(processor, path, test)
+			elapsed := time.Since(start)
+
+			if !result.Successful {
+				return measurement, fmt.Errorf("%s fails test %d", solution.Path, i+1)
+			}
+			if elapsed > measurement.Time {
+				measurement.Time = elapsed
+			}
+		}
+	}
+	return measurement, nil
+}
+
+// WriteTimeLimits replaces the time limits in the task section of the task
+// file. The file is edited as text so that the rest of it is kept as is.
+func (taskConfig TaskConfig) WriteTimeLimits(limits map[string]time.Duration) error {
+	data, err := ioutil.ReadFile(taskConfig.path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	start := -1
+	for i, line := range lines {
+		if strings.TrimRight(line, " \r\n") == "task:" {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return errors.New("No task section in " + taskConfig.path)
+	}
+
+	indent := "  "
+	end := start + 1
+	block := make([]string, 0)
+	for ; end < len(lines); end++ {
+		line := lines[end]
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.TrimSpace(line) != "" && len(trimmed) == len(line) {
+			break
+		}
+		if len(block) == 0 && strings.TrimSpace(line) != "" {
+			indent = line[:len(line)-len(trimmed)]
+		}
+		block = append(block, line)
+	}
+
+	kept := make([]string, 0, len(block))
+	skipping := false
+	for _, line := range block {
+		trimmed := strings.TrimLeft(line, " ")
+		depth := len(line) - len(trimmed)
+		if skipping && strings.TrimSpace(line) != "" && depth > len(indent) {
+			continue
+		}
+		skipping = depth == len(indent) && strings.HasPrefix(trimmed, "time_limits:")
+		if !skipping {
+			kept = append(kept, line)
+		}
+	}
+	trailing := make([]string, 0)
+	for len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+		trailing = append([]string{kept[len(kept)-1]}, trailing...)
+		kept = kept[:len(kept)-1]
+	}
+	if len(kept) > 0 && !strings.HasSuffix(kept[len(kept)-1], "\n") {
+		kept[len(kept)-1] += "\n"
+	}
+
+	names := make([]string, 0, len(limits))
+	for name := range limits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	section := indent + "time_limits:\n"
+	for _, name := range names {
+		section += indent + indent + name + ": " + limits[name].String() + "\n"
+	}
+
+	out := strings.Join(lines[:start+1], "") + strings.Join(kept, "") + section +
+		strings.Join(trailing, "") + strings.Join(lines[end:], "")
+	if err = yaml.Unmarshal([]byte(out), &TaskConfig{}); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(taskConfig.path, []byte(out), 0644)
+}
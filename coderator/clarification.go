@@ -0,0 +1,146 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"sync"
+	"time"
+)
+
+type Clarification struct {
+	Id        uint64
+	ContestId uint64
+	TaskId    uint64
+	User      string
+	Question  string
+	Answer    string
+	Public    bool
+	Asked     time.Time
+	Answered  time.Time
+}
+
+type Announcement struct {
+	Id        uint64
+	ContestId uint64
+	TaskId    uint64
+	Text      string
+	Time      time.Time
+}
+
+var clarifications = make([]Clarification, 0)
+var announcements = make([]Announcement, 0)
+var clarificationsMutex sync.RWMutex
+
+func AskClarification(contest Contest, taskId uint64, user string, question string) Clarification {
+	clarificationsMutex.Lock()
+	defer clarificationsMutex.Unlock()
+
+	clarification := Clarification{
+		Id:        uint64(len(clarifications) + 1),
+		ContestId: contest.Id,
+		TaskId:    taskId,
+		User:      user,
+		Question:  question,
+		Asked:     time.Now(),
+	}
+	clarifications = append(clarifications, clarification)
+	return clarification
+}
+
+// AnswerClarification answers the question and notifies either the asking
+// participant only or, when the answer is public, everyone in the contest.
+func AnswerClarification(id uint64, answer string, public bool) *Clarification {
+	clarificationsMutex.Lock()
+	if id == 0 || id > uint64(len(clarifications)) {
+		clarificationsMutex.Unlock()
+		return nil
+	}
+	clarification := &clarifications[id-1]
+	clarification.Answer = answer
+	clarification.Public = public
+	clarification.Answered = time.Now()
+	answered := *clarification
+	clarificationsMutex.Unlock()
+
+	event := Event{Type: EventClarification, Data: answered}
+	if !public {
+		event.User = answered.User
+	}
+	events.Publish(answered.ContestId, event)
+	return &answered
+}
+
+func FindClarificationById(id uint64) *Clarification {
+	clarificationsMutex.RLock()
+	defer clarificationsMutex.RUnlock()
+
+	if id == 0 || id > uint64(len(clarifications)) {
+		return nil
+	}
+	clarification := clarifications[id-1]
+	return &clarification
+}
+
+// FindClarifications returns the clarifications of the contest visible to
+// the user. An empty user stands for an administrator who sees all of them.
+func FindClarifications(contest Contest, user string) []Clarification {
+	clarificationsMutex.RLock()
+	defer clarificationsMutex.RUnlock()
+
+	found := make([]Clarification, 0)
+	for _, clarification := range clarifications {
+		if clarification.ContestId != contest.Id {
+			continue
+		}
+		if user == "" || clarification.User == user || clarification.Public {
+			found = append(found, clarification)
+		}
+	}
+	return found
+}
+
+func Announce(contest Contest, taskId uint64, text string) Announcement {
+	clarificationsMutex.Lock()
+	announcement := Announcement{
+		Id:        uint64(len(announcements) + 1),
+		ContestId: contest.Id,
+		TaskId:    taskId,
+		Text:      text,
+		Time:      time.Now(),
+	}
+	announcements = append(announcements, announcement)
+	clarificationsMutex.Unlock()
+
+	events.Publish(contest.Id, Event{Type: EventAnnouncement, Data: announcement})
+	return announcement
+}
+
+func FindAnnouncements(contest Contest) []Announcement {
+	clarificationsMutex.RLock()
+	defer clarificationsMutex.RUnlock()
+
+	found := make([]Announcement, 0)
+	for _, announcement := range announcements {
+		if announcement.ContestId == contest.Id {
+			found = append(found, announcement)
+		}
+	}
+	return found
+}
@@ -19,25 +19,1242 @@
 
 package coderator
 
-import "testing"
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestQueue(t *testing.T) {
-	task := Task{}
-	task.Id = 1
-	Queue(task)
+	submission := Submission{}
+	submission.Id = 1
+	Queue(submission)
 
-	if !IsVerificationQueued(task) {
+	if !IsVerificationQueued(submission) {
 		t.Fail()
 	}
 }
 
 func TestDequeue(t *testing.T) {
+	submission := Submission{}
+	submission.Id = 1
+	Queue(submission)
+	Dequeue(submission)
+
+	if IsVerificationQueued(submission) {
+		t.Fail()
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Requests: 2, Period: time.Minute})
+	now := time.Now()
+
+	if _, _, allowed := limiter.Allow("user", now); !allowed {
+		t.Fail()
+	}
+	if _, _, allowed := limiter.Allow("user", now); !allowed {
+		t.Fail()
+	}
+	if _, _, allowed := limiter.Allow("user", now); allowed {
+		t.Fail()
+	}
+	if _, _, allowed := limiter.Allow("other", now); !allowed {
+		t.Fail()
+	}
+	if _, _, allowed := limiter.Allow("user", now.Add(time.Minute)); !allowed {
+		t.Fail()
+	}
+
+	limiter.Allow("late", now.Add(2*time.Minute))
+	if _, exists := limiter.requests["late"]; !exists || len(limiter.requests) != 1 {
+		t.Errorf("expired clients kept: %v", limiter.requests)
+	}
+}
+
+func TestCheckQuota(t *testing.T) {
+	task := Task{}
+	task.Id = 1026
+	quota := Quota{Attempts: 2, Cooldown: time.Minute}
+
+	if remaining, _, allowed := CheckQuota(quota, task, "user", time.Now()); !allowed || remaining != 2 {
+		t.Fail()
+	}
+
+	NewSubmission(task, "user", "", nil, "")
+	if _, wait, allowed := CheckQuota(quota, task, "user", time.Now()); allowed || wait <= 0 {
+		t.Fail()
+	}
+
+	NewSubmission(task, "user", "", nil, "")
+	if remaining, _, allowed := CheckQuota(quota, task, "user", time.Now().Add(time.Hour)); allowed || remaining != 0 {
+		t.Fail()
+	}
+}
+
+func TestSubmitWithinQuota(t *testing.T) {
+	task := Task{}
+	task.Id = 1126
+	quota := Quota{Attempts: 3}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			SubmitWithinQuota(quota, task, "user", "", nil, "", time.Now())
+		}()
+	}
+	wg.Wait()
+
+	if len(FindSubmissionsByTaskAndUser(task.Id, "user")) != quota.Attempts {
+		t.Fail()
+	}
+}
+
+func TestClientId(t *testing.T) {
+	previous := application
+	defer func() { application = previous }()
+	application = &ApplicationConfig{Users: []UserConfig{{Name: "alice", Token: "secret"}}}
+
+	r := httptest.NewRequest("POST", "/tasks/1/solve", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-Coderator-User", "alice")
+	if clientId(r) != "192.0.2.1" {
+		t.Fail()
+	}
+
+	r.Header.Set("Authorization", "Bearer wrong")
+	if clientId(r) != "192.0.2.1" {
+		t.Fail()
+	}
+
+	r.Header.Set("Authorization", "Bearer secret")
+	if clientId(r) != "alice" {
+		t.Fail()
+	}
+}
+
+type contestList []Contest
+
+func (list contestList) AllContests() ([]Contest, error) {
+	return list, nil
+}
+
+func (list contestList) FindContestById(id uint64) (*Contest, error) {
+	for _, contest := range list {
+		if contest.Id == id {
+			return &contest, nil
+		}
+	}
+	return nil, nil
+}
+
+func TestContestDenial(t *testing.T) {
+	now := time.Now()
+	running := Contest{Id: 1027, Tasks: []uint64{1, 2}, Start: now.Add(-time.Hour), End: now.Add(time.Hour)}
+	over := Contest{Id: 1028, Tasks: []uint64{2, 3}, Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)}
+	upcoming := Contest{Id: 1029, Tasks: []uint64{5}, Start: now.Add(time.Hour), End: now.Add(2 * time.Hour)}
+
+	previous := contests
+	defer func() { contests = previous }()
+	contests = contestList{running, over, upcoming}
+	Register(running, "alice")
+	Register(over, "bob")
+
+	cases := []struct {
+		task   uint64
+		user   string
+		denial string
+	}{
+		{1, "alice", ""},
+		{1, "bob", ErrorNotRegistered},
+		{2, "alice", ""},
+		{2, "carol", ""},
+		{3, "carol", ""},
+		{4, "carol", ""},
+		{5, "alice", ErrorNotStarted},
+	}
+	for _, c := range cases {
+		if denial, err := contestDenial(c.task, c.user, now); err != nil || denial != c.denial {
+			t.Errorf("task %d, %s: %q %v", c.task, c.user, denial, err)
+		}
+	}
+}
+
+func TestFindClarifications(t *testing.T) {
+	contest := Contest{Id: 1128}
+	private := AskClarification(contest, 1, "alice", "Is n positive?")
+	public := AskClarification(contest, 1, "bob", "Can n be zero?")
+	AskClarification(Contest{Id: 1129}, 1, "alice", "Other contest")
+	AnswerClarification(public.Id, "Yes", true)
+
+	ids := func(found []Clarification) []uint64 {
+		result := make([]uint64, 0, len(found))
+		for _, clarification := range found {
+			result = append(result, clarification.Id)
+		}
+		return result
+	}
+	if found := ids(FindClarifications(contest, "alice")); len(found) != 2 || found[0] != private.Id || found[1] != public.Id {
+		t.Errorf("alice: %v", found)
+	}
+	if found := ids(FindClarifications(contest, "carol")); len(found) != 1 || found[0] != public.Id {
+		t.Errorf("carol: %v", found)
+	}
+	if found := FindClarifications(contest, ""); len(found) != 2 {
+		t.Errorf("admin: %v", ids(found))
+	}
+}
+
+func TestAnswerClarification(t *testing.T) {
+	contest := Contest{Id: 1130}
+	asker, cancelAsker := events.Subscribe(contest.Id, "alice")
+	defer cancelAsker()
+	other, cancelOther := events.Subscribe(contest.Id, "bob")
+	defer cancelOther()
+
+	clarification := AskClarification(contest, 1, "alice", "Is n positive?")
+	if AnswerClarification(0, "No", false) != nil || AnswerClarification(clarification.Id+1000, "No", false) != nil {
+		t.Error("answered a missing clarification")
+	}
+	answered := AnswerClarification(clarification.Id, "Yes", false)
+	if answered == nil || answered.Answer != "Yes" || answered.Public {
+		t.Fatalf("%+v", answered)
+	}
+
+	select {
+	case event := <-asker:
+		if event.Type != EventClarification || event.User != "alice" {
+			t.Errorf("%+v", event)
+		}
+	default:
+		t.Error("the asker was not notified")
+	}
+	select {
+	case event := <-other:
+		t.Errorf("private answer delivered to another participant: %+v", event)
+	default:
+	}
+}
+
+func TestBrokerPublish(t *testing.T) {
+	broker := NewBroker()
+	alice, _ := broker.Subscribe(1, "alice")
+	bob, _ := broker.Subscribe(1, "bob")
+	admin, _ := broker.Subscribe(1, "")
+	elsewhere, _ := broker.Subscribe(2, "alice")
+
+	broker.Publish(1, Event{Type: EventClarification, User: "alice"})
+	broker.Publish(1, Event{Type: EventAnnouncement})
+
+	count := func(events <-chan Event) int {
+		n := 0
+		for {
+			select {
+			case <-events:
+				n++
+			default:
+				return n
+			}
+		}
+	}
+	if count(alice) != 2 || count(bob) != 1 || count(admin) != 2 || count(elsewhere) != 0 {
+		t.Fail()
+	}
+
+	slow, cancel := broker.Subscribe(1, "")
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			broker.Publish(1, Event{Type: EventAnnouncement})
+		}
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+	if n := count(slow); n == 0 || n >= 100 {
+		t.Errorf("%d events buffered", n)
+	}
+
+	cancel()
+	broker.Publish(1, Event{Type: EventAnnouncement})
+	if count(slow) != 0 {
+		t.Error("event delivered after cancel")
+	}
+}
+
+// noTasks is a repository without tasks, so rejudged submissions end with
+// an internal error right away.
+type noTasks struct {
+	Config
+}
+
+func (repository noTasks) FindTaskById(id uint64) (*Task, error) {
+	return nil, nil
+}
+
+// waitingTasks is a repository without tasks answering only once released.
+type waitingTasks struct {
+	Config
+	release chan bool
+}
+
+func (repository waitingTasks) FindTaskById(id uint64) (*Task, error) {
+	<-repository.release
+	return nil, nil
+}
+
+func TestRejudgeAll(t *testing.T) {
+	previous := database
+	database = noTasks{}
+	defer func() { database = previous }()
+
 	task := Task{}
-	task.Id = 1
-	Queue(task)
-	Dequeue(task)
+	task.Id = 1030
+	judgedAt := time.Now().Add(-time.Hour)
+	judged := NewSubmission(task, "alice", "sh", nil, "")
+	judged.Result = TestFailed
+	judged.Results = []bool{true, false}
+	judged.Judged = judgedAt
+	UpdateSubmission(judged)
+	waiting := NewSubmission(task, "bob", "sh", nil, "")
+	Queue(waiting)
+	defer Dequeue(waiting)
+
+	ids := RejudgeAll([]Submission{judged, waiting})
+	if len(ids) != 1 || ids[0] != judged.Id {
+		t.Fatalf("%v", ids)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for IsVerificationQueued(judged) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	rejudged := FindSubmissionById(judged.Id)
+	if len(rejudged.History) != 1 || rejudged.Result != InternalError {
+		t.Fatalf("%+v", rejudged)
+	}
+	verdict := rejudged.History[0]
+	if verdict.Result != TestFailed || len(verdict.Results) != 2 || verdict.Results[1] || !verdict.Judged.Equal(judgedAt) {
+		t.Errorf("%+v", verdict)
+	}
+	if skipped := FindSubmissionById(waiting.Id); len(skipped.History) != 0 || skipped.Result != 0 {
+		t.Errorf("%+v", skipped)
+	}
+	// The verification of the submission waits until every rejudge has
+	// tried to queue it.
+	release := make(chan bool)
+	database = waitingTasks{release: release}
+	again := NewSubmission(task, "carol", "sh", nil, "")
+	queued := make(chan []uint64, 10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			queued <- RejudgeAll([]Submission{again})
+		}()
+	}
+	count := 0
+	for i := 0; i < 10; i++ {
+		count += len(<-queued)
+	}
+	close(release)
+	if count != 1 {
+		t.Errorf("queued %d times", count)
+	}
+	for IsVerificationQueued(again) && time.Now().Before(deadline.Add(5*time.Second)) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestComputeScoreboard(t *testing.T) {
+	start := time.Date(2018, 10, 1, 10, 0, 0, 0, time.UTC)
+	contest := Contest{
+		Tasks:   []uint64{1, 2},
+		Start:   start,
+		End:     start.Add(5 * time.Hour),
+		Scoring: ScoringICPC,
+	}
+	submissions := []Submission{
+		{User: "alice", TaskId: 1, Time: start.Add(10 * time.Minute), Result: TestFailed, Results: []bool{true, false}},
+		{User: "alice", TaskId: 1, Time: start.Add(30 * time.Minute), Result: Success, Results: []bool{true, true}},
+		{User: "bob", TaskId: 1, Time: start.Add(20 * time.Minute), Result: Success, Results: []bool{true, true}},
+		{User: "bob", TaskId: 2, Time: start.Add(4 * time.Hour), Result: Success, Results: []bool{true}},
+	}
+
+	scoreboard := ComputeScoreboard(contest, []string{"alice", "bob"}, submissions, contest.End)
+	if scoreboard.Rows[0].User != "bob" || scoreboard.Rows[0].Solved != 2 {
+		t.Fail()
+	}
+	if scoreboard.Rows[1].User != "alice" || scoreboard.Rows[1].Penalty != 50 {
+		t.Fail()
+	}
+
+	frozen := ComputeScoreboard(contest, []string{"alice", "bob"}, submissions, start.Add(3*time.Hour))
+	if frozen.Rows[0].User != "bob" || frozen.Rows[0].Tasks[1].Pending != 1 || frozen.Rows[0].Rank != 1 {
+		t.Fail()
+	}
+	if frozen.Rows[1].Rank != 2 {
+		t.Fail()
+	}
+}
+
+func TestLintTasks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.yml": "task:\n  id: 1\n  title: A\n  text: A\n  processor: python3.6\ntests:\n  - input: 1\n    output: 1\n",
+		"b.yml": "task:\n  id: 1\n  title: B\n  text: B\n  processor: ruby\ntests:\n  - input: 1\n    output: 1\n    comparator:\n      name: fuzzy\n",
+		"c.yml": "task:\n  id: 3\n  title: C\n  text: C\n  processor: python3.6\ntests:\n  comparator:\n    name: exact\n",
+	}
+	for name, content := range files {
+		if err = ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	appConfig := ApplicationConfig{Processors: []LanguageProcessor{{Name: "python", Version: "3.6"}}}
+	problems := LintTasks(dir, appConfig)
+	if len(problems) != 4 {
+		t.Fatal(problems)
+	}
+	for _, problem := range problems[:3] {
+		if filepath.Base(problem.File) != "b.yml" {
+			t.Error(problem)
+		}
+	}
+	if filepath.Base(problems[3].File) != "c.yml" {
+		t.Error(problems[3])
+	}
+}
+
+func TestShrink(t *testing.T) {
+	counterexample := &Counterexample{Input: "1\n2\n3\n4\n5\n6\n"}
+	fails := func(input string) (*Counterexample, error) {
+		if strings.Contains(input, "3\n") && strings.Contains(input, "5\n") {
+			return &Counterexample{Input: input}, nil
+		}
+		return nil, nil
+	}
+
+	shrunk := shrink(counterexample, fails)
+	if shrunk.Input != "3\n5\n" {
+		t.Errorf("%q", shrunk.Input)
+	}
+}
+
+func TestWriteTimeLimits(t *testing.T) {
+	file, err := ioutil.TempFile("", "task")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+
+	content := "task:\n  id: 1\n  time_limits:\n    python3.6: 5s\n    go1.10: 1s\n\ntests:\n  - input: 1\n    output: 1\n"
+	if err = ioutil.WriteFile(file.Name(), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	taskConfig := TaskConfig{path: file.Name()}
+	if err = taskConfig.WriteTimeLimits(map[string]time.Duration{"python3.6": 300 * time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "task:\n  id: 1\n  time_limits:\n    python3.6: 300ms\n\ntests:\n  - input: 1\n    output: 1\n"
+	if string(data) != expected {
+		t.Errorf("%q", data)
+	}
+}
+
+func TestTaskLimits(t *testing.T) {
+	python := LanguageProcessor{Name: "python", Version: "3.6", TimeMultiplier: 3, ExtraMemory: 64}
+	gcc := LanguageProcessor{Name: "gcc", Version: "7"}
+	task := Task{
+		TimeLimit:   time.Second,
+		TimeLimits:  map[string]time.Duration{"gcc7": 500 * time.Millisecond},
+		MemoryLimit: 256,
+	}
+
+	if limits := task.Limits(python); limits != (ResourceLimits{3 * time.Second, 320}) {
+		t.Error(limits)
+	}
+	if limits := task.Limits(gcc); limits != (ResourceLimits{500 * time.Millisecond, 256}) {
+		t.Error(limits)
+	}
+}
+
+func TestAllowedProcessors(t *testing.T) {
+	appConfig := ApplicationConfig{Processors: []LanguageProcessor{
+		{Name: "python", Version: "3.6"},
+		{Name: "gcc", Version: "7"},
+		{Name: "ruby", Version: "2.5"},
+	}}
+
+	task := Task{Processor: "python3.6", Processors: []string{"gcc7"}}
+	if !task.AllowsProcessor("gcc7") || task.AllowsProcessor("ruby2.5") {
+		t.Error(task)
+	}
+	if processors := task.AllowedProcessors(appConfig); len(processors) != 2 {
+		t.Error(processors)
+	}
+
+	task.Processors = []string{AnyProcessor}
+	if processors := task.AllowedProcessors(appConfig); len(processors) != 3 {
+		t.Error(processors)
+	}
+}
+
+func TestDetectProcessor(t *testing.T) {
+	python := LanguageProcessor{Name: "python", Version: "3.6", Extensions: []string{".py"}, Keywords: []string{"def ", "import "}}
+	python2 := LanguageProcessor{Name: "python", Version: "2.7", Extensions: []string{".py"}, Keywords: []string{"def ", "import "}}
+	ruby := LanguageProcessor{Name: "ruby", Version: "2.5", Extensions: []string{".rb"}, Keywords: []string{"def ", "end\n", "puts "}}
+	processors := []LanguageProcessor{python, python2, ruby}
+
+	cases := []struct {
+		filename string
+		source   string
+		expected string
+	}{
+		{"solution.rb", "puts 1\n", "ruby2.5"},
+		{"solution.py", "print(1)\n", ""},
+		{"solution.py", "#!/usr/bin/env python3\nprint(1)\n", "python3.6"},
+		{"source", "def f(x)\n  x\nend\nputs f(1)\n", "ruby2.5"},
+	}
+	for _, c := range cases {
+		name := ""
+		if detected := DetectProcessor(processors, c.filename, []byte(c.source)); detected != nil {
+			name = detected.Name + detected.Version
+		}
+		if name != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.filename, c.expected, name)
+		}
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	write := func(names ...string) []byte {
+		buffer := &bytes.Buffer{}
+		writer := zip.NewWriter(buffer)
+		for _, name := range names {
+			file, err := writer.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.Write([]byte("print(1)\n"))
+		}
+		writer.Close()
+		return buffer.Bytes()
+	}
+
+	files, err := ExtractArchive("solution.zip", write("main.py", "lib/util.py", "lib/../helper.py"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || files[1].Name != "lib/util.py" || files[2].Name != "helper.py" {
+		t.Error(files)
+	}
+
+	for _, name := range []string{"../main.py", "/etc/passwd", "lib/../../main.py"} {
+		if _, err = ExtractArchive("solution.zip", write(name)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestWorkDirs(t *testing.T) {
+	root, err := ioutil.TempDir("", "workdirs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	application = &ApplicationConfig{WorkDir: root}
+	defer func() { application = nil }()
+
+	submission := Submission{Id: 7, Entry: "main.py", Files: []SourceFile{{"main.py", []byte("print(1)\n")}}}
+	dir, entry, err := SaveSolution(submission)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry != filepath.Join(dir, "main.py") {
+		t.Error(entry)
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Error(info, err)
+	}
+
+	other := filepath.Join(root, "other")
+	if err = ioutil.WriteFile(other, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = CleanWorkDirs(root); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("working directory is not removed")
+	}
+	if _, err = os.Stat(other); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFunctionTests(t *testing.T) {
+	taskConfig := TaskConfig{Tests: []Test{{
+		Args:   []interface{}{[]interface{}{1, -2}, "x"},
+		Result: map[interface{}]interface{}{"sum": -1.0},
+	}}}
+
+	tests, err := taskConfig.MaterializeTests(ApplicationConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	test := tests[0]
+	if test.Input != `[[1,-2],"x"]` || test.Output != `{"sum":-1}` || test.Comparator.Name != "json" {
+		t.Error(test)
+	}
+	if !test.Comparator.Compare(`{ "sum": -1.0 }`, test.Output) || test.Comparator.Compare(`{"sum": 1}`, test.Output) {
+		t.Error("unexpected comparison")
+	}
+
+	if !CheckValue("[]float", []interface{}{1, 2.5}) || CheckValue("[]int", []interface{}{1, 2.5}) {
+		t.Error("unexpected type check")
+	}
+}
+
+func TestStarter(t *testing.T) {
+	file, err := ioutil.TempFile("", "starter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("def {{.Function.Name}}({{range $i, $param := .Function.Params}}{{if $i}}, {{end}}{{$param.Name}}{{end}}):\n")
+	file.Close()
+
+	python := LanguageProcessor{Name: "python", Version: "3.6", StarterTemplate: file.Name()}
+	task := Task{Function: &Function{Name: "add", Params: []Param{{"a", TypeInt}, {"b", TypeInt}}, Result: TypeInt}}
+	if code, err := task.Starter(python); err != nil || code != "def add(a, b):\n" {
+		t.Errorf("%q %v", code, err)
+	}
+
+	task.Templates = map[string]string{"python3.6": "print()\n"}
+	if code, err := task.Starter(python); err != nil || code != "print()\n" {
+		t.Errorf("%q %v", code, err)
+	}
+}
+
+func TestParseReports(t *testing.T) {
+	tap := "1..4\nok 1 - zero\nnot ok 2 - negative\n# expected 1.5\nok 3 - slow # SKIP no time\n"
+	results, err := ParseTAP(tap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || !results[0].Successful || results[1].Successful || results[1].Output != "expected 1.5" || results[2].Successful {
+		t.Errorf("%+v", results)
+	}
+
+	junit := `<testsuites><testsuite name="abs">
+<testcase classname="abs" name="zero"/>
+<testcase classname="abs" name="negative"><failure message="wrong">-1.5 != 1.5</failure></testcase>
+<testcase classname="abs" name="slow"><skipped/></testcase>
+</testsuite></testsuites>`
+	results, err = ParseJUnit([]byte(junit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Name != "abs.zero" || !results[0].Successful || results[1].Successful {
+		t.Errorf("%+v", results)
+	}
+}
+
+func TestRunSuite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "suite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "module"), []byte(""), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "empty.sh"), []byte("echo '<testsuite name=\"none\"></testsuite>' > $1\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "silent.sh"), []byte("exit 1\n"), 0644)
+
+	shell := LanguageProcessor{Name: "sh", Path: "/bin/sh"}
+	appConfig := ApplicationConfig{
+		Processors: []LanguageProcessor{shell},
+		Runners:    []RunnerConfig{{Name: "sh", Processor: "sh", Args: []string{"{suite}", "{report}"}, Format: FormatJUnit}},
+	}
+	run := func(suite string) error {
+		_, err := RunSuite(appConfig, "sh", ResourceLimits{}, "", filepath.Join(dir, "module"), "module", filepath.Join(dir, suite))
+		return err
+	}
+
+	if err := run("empty.sh"); err == nil || err.Error() != "no tests" {
+		t.Errorf("%v", err)
+	}
+	err = run("silent.sh")
+	if _, ok := err.(ReportError); !ok || !strings.Contains(err.Error(), "report.xml") {
+		t.Errorf("%v", err)
+	}
+
+	submission := filepath.Join(dir, "submission")
+	os.MkdirAll(filepath.Join(submission, "lib"), 0755)
+	ioutil.WriteFile(filepath.Join(submission, "main.sh"), []byte(""), 0644)
+	ioutil.WriteFile(filepath.Join(submission, "lib", "helper.sh"), []byte(""), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "files.sh"), []byte("test -f lib/helper.sh && test -f module && echo '<testsuite><testcase name=\"files\"/></testsuite>' > $1\n"), 0644)
+	results, err := RunSuite(appConfig, "sh", ResourceLimits{}, submission, filepath.Join(submission, "main.sh"), "module", filepath.Join(dir, "files.sh"))
+	if err != nil || len(results) != 1 || !results[0].Successful {
+		t.Errorf("%+v %v", results, err)
+	}
+}
+
+func TestMutationJudge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mutation")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"correct":  "fine\n",
+		"buggy":    "bug\n",
+		"subtle":   "fine\n",
+		"suite.sh": "echo 1..1\nif grep -q bug module; then echo 'not ok 1 - check'; else echo 'ok 1 - check'; fi\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shell := LanguageProcessor{Name: "sh", Path: "/bin/sh"}
+	appConfig := ApplicationConfig{
+		Processors: []LanguageProcessor{shell},
+		Runners:    []RunnerConfig{{Name: "sh", Processor: "sh", Args: []string{"{suite}"}, Format: FormatTAP}},
+	}
+	taskConfig := TaskConfig{
+		Task:     Task{Type: TaskTypeMutation},
+		Mutation: &Mutation{Runner: "sh", Module: "module", Implementation: "correct", Mutants: []string{"buggy", "subtle"}},
+		dir:      dir,
+	}
+
+	results, err := MutationJudge{}.Run(taskConfig, appConfig, shell, filepath.Join(dir, "suite.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || !results[0].Successful || results[1].Successful {
+		t.Errorf("%+v", results)
+	}
+
+	taskConfig.Mutation.Implementation = "buggy"
+	results, err = MutationJudge{}.Run(taskConfig, appConfig, shell, filepath.Join(dir, "suite.sh"))
+	if err != nil || results[0].Successful || results[1].Successful {
+		t.Errorf("%+v %v", results, err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "looping"), []byte("loop\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "silent"), []byte("silent\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "checks.sh"), []byte("grep -q loop module && while :; do :; done\ngrep -q silent module && exit 1\necho 1..1\necho 'ok 1 - check'\n"), 0644)
+	taskConfig.Task.TimeLimit = 200 * time.Millisecond
+	taskConfig.Mutation.Implementation = "correct"
+	taskConfig.Mutation.Mutants = []string{"looping", "silent"}
+	results, err = MutationJudge{}.Run(taskConfig, appConfig, shell, filepath.Join(dir, "checks.sh"))
+	if err != nil || len(results) != 2 || !results[0].Successful || results[1].Successful {
+		t.Errorf("%+v %v", results, err)
+	}
+
+	taskConfig.Mutation.Mutants = []string{"missing"}
+	results, err = MutationJudge{}.Run(taskConfig, appConfig, shell, filepath.Join(dir, "checks.sh"))
+	if err == nil {
+		t.Errorf("%+v", results)
+	}
+}
+
+func TestHTTPTest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte(`{"b": [1, 2], "a": 0.5}`))
+	}))
+	defer server.Close()
+
+	address := strings.TrimPrefix(server.URL, "http://")
+	test := Test{
+		Request:  &HTTPRequest{Method: http.MethodPost, Path: "/"},
+		Response: &HTTPResponse{Status: http.StatusOK, Headers: map[string]string{"Content-Type": "application/json"}, Body: `{"a": 0.5, "b": [1, 2]}`},
+	}
+	if result := runHTTPTest(http.Client{}, address, test); !result.Successful || result.Name != "POST /" {
+		t.Errorf("%+v", result)
+	}
+
+	test.Request.Method = ""
+	if result := runHTTPTest(http.Client{}, address, test); result.Successful || result.Output != "405" {
+		t.Errorf("%+v", result)
+	}
+}
+
+func TestHTTPJudgeStopsProcessGroup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "service.sh"), []byte("sleep 30 &\necho $! > child.pid\nwait\n"), 0644)
+	taskConfig := TaskConfig{Service: &Service{Startup: 300 * time.Millisecond}}
+
+	start := time.Now()
+	results, err := HTTPJudge{}.Run(taskConfig, ApplicationConfig{}, LanguageProcessor{Name: "sh", Path: "/bin/sh"}, filepath.Join(dir, "service.sh"))
+	if err != nil || len(results) != 1 || results[0].Error == nil {
+		t.Errorf("%+v %v", results, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("stopped after %v", elapsed)
+	}
+
+	pid, _ := ioutil.ReadFile(filepath.Join(dir, "child.pid"))
+	stat, err := ioutil.ReadFile(filepath.Join("/proc", strings.TrimSpace(string(pid)), "stat"))
+	if err == nil && !strings.Contains(string(stat), ") Z ") {
+		t.Errorf("child left running: %s", stat)
+	}
+}
 
-	if IsVerificationQueued(task) {
+func TestOutputJudge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "outputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "answers"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "answers", "1.out"), []byte("3\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "7.txt"), []byte("5"), 0644)
+
+	taskConfig := TaskConfig{
+		Task:  Task{Type: TaskTypeOutput},
+		Tests: []Test{{Input: "1 2", Output: "3"}, {Input: "2 2", Output: "4"}, {Id: 7, Input: "2 3", Output: "5\n"}},
+	}
+	results, err := OutputJudge{}.Run(taskConfig, ApplicationConfig{}, LanguageProcessor{}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || !results[0].Successful || results[1].Successful || results[1].Output != "missing" || !results[2].Successful || results[2].Name != "7" {
+		t.Errorf("%+v", results)
+	}
+}
+
+func TestCompareTrailingWhitespace(t *testing.T) {
+	for _, config := range []ComparatorConfig{{}, {Name: "ordered"}} {
+		if !config.Compare("1 2\n", "1 2") || !config.Compare("1 2", "1 2 \r\n\n") {
+			t.Error(config.Name)
+		}
+	}
+	if (ComparatorConfig{}).Compare(" 1 2", "1 2") {
+		t.Fail()
+	}
+}
+
+func TestSolutionMatches(t *testing.T) {
+	cases := []struct {
+		expected string
+		verdict  string
+		matches  bool
+	}{
+		{"", VerdictAccepted, true},
+		{"", VerdictWrongAnswer, false},
+		{VerdictWrongAnswer, VerdictWrongAnswer, true},
+		{VerdictWrongAnswer, VerdictTimeLimit, false},
+		{VerdictRejected, VerdictRuntimeError, true},
+		{VerdictRejected, VerdictTimeLimit, true},
+		{VerdictRejected, VerdictAccepted, false},
+	}
+	for _, c := range cases {
+		if (Solution{Verdict: c.expected}).Matches(c.verdict) != c.matches {
+			t.Errorf("expected %q, got %q", c.expected, c.verdict)
+		}
+	}
+}
+
+func TestCheckSolutions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "solutions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"double.sh": "echo $(($1 * 2))\n",
+		"wrong.sh":  "echo 0\n",
+		"crash.sh":  "exit 1\n",
+		"slow.sh":   "while :; do :; done\n",
+	}
+	for name, content := range files {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	shell := LanguageProcessor{Name: "sh", Path: "/bin/sh"}
+	appConfig := ApplicationConfig{Processors: []LanguageProcessor{shell}}
+	taskConfig := TaskConfig{
+		Task:  Task{Processor: "sh", TimeLimit: 200 * time.Millisecond},
+		Tests: []Test{{Input: "2", Output: "4"}, {Input: "3", Output: "6"}},
+		Solutions: []Solution{
+			{Path: "double.sh", Processor: "sh"},
+			{Path: "wrong.sh", Processor: "sh", Verdict: VerdictWrongAnswer},
+			{Path: "crash.sh", Processor: "sh", Verdict: VerdictRejected},
+			{Path: "slow.sh", Processor: "sh"},
+			{Path: "wrong.sh", Processor: "sh"},
+			{Path: "double.sh", Processor: "cobol"},
+		},
+		dir:  dir,
+		path: filepath.Join(dir, "double.yml"),
+	}
+
+	problems := CheckSolutions(taskConfig, appConfig)
+	expected := []string{
+		"slow.sh: expected accepted, got time-limit-exceeded on tests 1, 2",
+		"wrong.sh: expected accepted, got wrong-answer on tests 1, 2",
+		"double.sh: unknown processor cobol",
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("%+v", problems)
+	}
+	for i, problem := range problems {
+		if problem.File != taskConfig.path || problem.Message != expected[i] {
+			t.Errorf("%+v", problem)
+		}
+	}
+}
+
+func TestValidateInputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "positive.sh"), []byte("read n\n[ \"$n\" -gt 0 ] || { echo 'n must be positive' >&2; exit 1; }\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "crash.sh"), []byte("echo 'Traceback: division by zero' >&2\nexit 3\n"), 0644)
+
+	shell := LanguageProcessor{Name: "sh", Path: "/bin/sh"}
+	appConfig := ApplicationConfig{Processors: []LanguageProcessor{shell}}
+	taskConfig := TaskConfig{
+		Task:      Task{Processor: "sh"},
+		Tests:     []Test{{Input: "5", Output: "5"}, {Input: "-1", Output: "1"}, {Input: "3", Output: "3"}},
+		Validator: &Program{Path: "positive.sh", Processor: "sh"},
+		dir:       dir,
+		path:      filepath.Join(dir, "task.yml"),
+	}
+
+	if violation, err := taskConfig.ValidateInput(appConfig, "5"); err != nil || violation != "" {
+		t.Errorf("%q %v", violation, err)
+	}
+	if violation, err := taskConfig.ValidateInput(appConfig, "0"); err != nil || violation != "n must be positive" {
+		t.Errorf("%q %v", violation, err)
+	}
+	problems := ValidateInputs(taskConfig, appConfig)
+	if len(problems) != 1 || problems[0].Message != "test 2: n must be positive" {
+		t.Errorf("%+v", problems)
+	}
+
+	taskConfig.Validator = &Program{Path: "crash.sh", Processor: "sh"}
+	if violation, err := taskConfig.ValidateInput(appConfig, "5"); err != nil || violation != "Traceback: division by zero" {
+		t.Errorf("crashing validator accepted the input: %q %v", violation, err)
+	}
+
+	taskConfig.Validator = &Program{Path: "missing.sh", Processor: "sh"}
+	if _, err := taskConfig.ValidateInput(appConfig, "5"); err == nil {
+		t.Error("missing validator accepted the input")
+	}
+	taskConfig.Validator = &Program{Path: "positive.sh", Processor: "cobol"}
+	problems = ValidateInputs(taskConfig, appConfig)
+	if len(problems) != 1 || !strings.HasPrefix(problems[0].Message, "validator: ") {
+		t.Errorf("%+v", problems)
+	}
+}
+
+func TestExternalComparator(t *testing.T) {
+	equal := ComparatorConfig{Name: "external", Values: map[string]string{"command": "true"}}
+	different := ComparatorConfig{Name: "external", Values: map[string]string{"command": "false"}}
+	missing := ComparatorConfig{Name: "external", Values: map[string]string{"command": "/nonexistent/compare"}}
+	if !equal.Compare("1", "2") || different.Compare("1", "1") || missing.Compare("1", "1") {
 		t.Fail()
 	}
+	if (ComparatorConfig{Name: "external"}).Comparator() != nil {
+		t.Error("external comparator without a command")
+	}
+}
+
+// inTaskDir runs the test in a temporary directory with the application
+// config and the task files, using the task files as the repository.
+func inTaskDir(t *testing.T, serve string, tasks map[string]string, test func(dir string)) {
+	dir, err := ioutil.TempDir("", "tasks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, TasksDir), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "serve"+Extension), []byte(serve), 0644)
+	for name, content := range tasks {
+		ioutil.WriteFile(filepath.Join(dir, TasksDir, name), []byte(content), 0644)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	previous := database
+	database = Config{}
+	defer func() {
+		database = previous
+	}()
+	test(dir)
+}
+
+func TestAddTest(t *testing.T) {
+	task := "task:\n  id: 1\n\ntests:\n  # samples\n  - input: \"1\"\n    output: \"2\"\n\nsolutions:\n  - path: double.sh\n"
+	inTaskDir(t, "", map[string]string{"double.yml": task, "bare.yml": "task:\n  id: 2\n"}, func(dir string) {
+		config := Config{}
+		if err := config.AddTest(1, Test{Input: "3", Output: "6"}); err != nil {
+			t.Fatal(err)
+		}
+		taskConfig, err := config.FindTaskConfigById(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(taskConfig.Tests) != 2 || taskConfig.Tests[1].Output != "6" || len(taskConfig.Solutions) != 1 {
+			t.Errorf("%+v", taskConfig)
+		}
+		data, _ := ioutil.ReadFile(filepath.Join(dir, TasksDir, "double.yml"))
+		if !strings.Contains(string(data), "# samples") {
+			t.Errorf("comment dropped:\n%s", data)
+		}
+
+		if err := config.AddTest(2, Test{Input: "1", Output: "1"}); err != nil {
+			t.Fatal(err)
+		}
+		if taskConfig, err = config.FindTaskConfigById(2); err != nil || len(taskConfig.Tests) != 1 {
+			t.Errorf("%+v %v", taskConfig, err)
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(input int) {
+				defer wg.Done()
+				if err := config.AddTest(1, Test{Input: fmt.Sprint(input), Output: fmt.Sprint(input * 2)}); err != nil {
+					t.Error(err)
+				}
+			}(10 + i%5)
+		}
+		wg.Wait()
+		if taskConfig, err = config.FindTaskConfigById(1); err != nil || len(taskConfig.Tests) != 7 {
+			t.Errorf("%+v %v", taskConfig, err)
+		}
+		if leftovers, _ := filepath.Glob(filepath.Join(dir, TasksDir, "*.tmp*")); len(leftovers) != 0 {
+			t.Errorf("%v", leftovers)
+		}
+	})
+}
+
+// readOnly is a repository of the task files refusing new tests.
+type readOnly struct {
+	Config
+}
+
+func (repository readOnly) AddTest(taskId uint64, test Test) error {
+	return errors.New("read-only repository")
+}
+
+func TestRunHack(t *testing.T) {
+	serve := "processors:\n  - name: sh\n    path: /bin/sh\n"
+	task := "task:\n  id: 1\n  processor: sh\n  time_limit: 500ms\n\ntests:\n  - input: \"2\"\n    output: \"4\"\n\nsolutions:\n  - path: double.sh\n    processor: sh\n"
+	submission := func(source string) Submission {
+		return Submission{Id: 1, TaskId: 1, User: "defender", Processor: "sh", Entry: "main.sh", Files: []SourceFile{{"main.sh", []byte(source)}}}
+	}
+
+	inTaskDir(t, serve, map[string]string{"double.yml": task, "double.sh": "echo $(($1 * 2))\n"}, func(dir string) {
+		buggy := submission("if [ \"$1\" = 3 ]; then echo 7; else echo $(($1 * 2)); fi\n")
+		hack, err := RunHack(Contest{}, buggy, "hacker", "2")
+		if err != nil || hack.Verdict != HackUnsuccessful {
+			t.Errorf("%+v %v", hack, err)
+		}
+		hack, err = RunHack(Contest{}, buggy, "hacker", "3")
+		if err != nil || hack.Verdict != HackSuccessful || hack.Message != VerdictWrongAnswer {
+			t.Errorf("%+v %v", hack, err)
+		}
+		if tests, _ := (Config{}).FindTestsByTaskId(1); len(tests) != 2 {
+			t.Errorf("hack input not added: %+v", tests)
+		}
+
+		looping := submission("while :; do :; done\n")
+		hack, err = RunHack(Contest{}, looping, "hacker", "2")
+		if err != nil || hack.Verdict != HackSuccessful || hack.Message != ErrTimeLimitExceeded.Error() {
+			t.Errorf("%+v %v", hack, err)
+		}
+		if tests, _ := (Config{}).FindTestsByTaskId(1); len(tests) != 2 {
+			t.Errorf("existing input added again: %+v", tests)
+		}
+
+		database = readOnly{}
+		hacks := len(FindHacksByContest(Contest{}))
+		if hack, err = RunHack(Contest{}, buggy, "hacker", "3"); err == nil || len(FindHacksByContest(Contest{})) != hacks {
+			t.Errorf("hack reported although its test was not added: %+v", hack)
+		}
+		database = Config{}
+
+		broken := submission("echo 4\n")
+		broken.Entry = "missing.sh"
+		if hack, err = RunHack(Contest{}, broken, "hacker", "2"); err == nil {
+			t.Errorf("%+v", hack)
+		}
+	})
+}
+
+func TestCachedTests(t *testing.T) {
+	serve := "processors:\n  - name: sh\n    path: /bin/sh\n"
+	task := "task:\n  id: 35\n  processor: sh\n\ntests:\n  - generator:\n      path: gen.sh\n      processor: sh\n      seed: 4\n\nsolutions:\n  - path: double.sh\n    processor: sh\n"
+	files := map[string]string{"double.yml": task, "double.sh": "echo $(($1 * 2))\n", "gen.sh": "echo $1\n"}
+
+	inTaskDir(t, serve, files, func(dir string) {
+		config := Config{}
+		taskConfig, err := config.FindTaskConfigById(35)
+		if err != nil {
+			t.Fatal(err)
+		}
+		appConfig, err := config.ApplicationConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err = taskConfig.CachedTests(); err == nil {
+			t.Error("tests generated by CachedTests")
+		}
+		if _, err = taskConfig.MaterializeTests(*appConfig); err != nil {
+			t.Fatal(err)
+		}
+		tests, err := config.FindTestsByTaskId(35)
+		if err != nil || len(tests) != 1 || tests[0].Input != "4\n" || tests[0].Output != "8" {
+			t.Errorf("%+v %v", tests, err)
+		}
+		cache, _ := filepath.Glob(filepath.Join(dir, TasksDir, CacheDir, "35", "*"))
+		if len(cache) != 2 {
+			t.Errorf("%v", cache)
+		}
+
+		ioutil.WriteFile(filepath.Join(dir, TasksDir, "gen.sh"), []byte("echo $(($1 + 1))\n"), 0644)
+		if tests, err = testsForJudging(35, *appConfig); err != nil || len(tests) != 1 || tests[0].Output != "10" {
+			t.Errorf("tests of the changed generator: %+v %v", tests, err)
+		}
+
+		ioutil.WriteFile(filepath.Join(dir, TasksDir, "gen.sh"), []byte("echo $(($1 + 2))\n"), 0644)
+		if _, err = config.FindTestsByTaskId(35); err == nil {
+			t.Error("outdated tests served")
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for tests, err = config.FindTestsByTaskId(35); err != nil && time.Now().Before(deadline); tests, err = config.FindTestsByTaskId(35) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil || tests[0].Output != "12" {
+			t.Errorf("tests not generated in the background: %+v %v", tests, err)
+		}
+	})
+}
+
+func TestStress(t *testing.T) {
+	serve := "processors:\n  - name: sh\n    path: /bin/sh\n  - name: dash\n    path: /bin/sh\n  - name: other\n    path: /bin/sh\n"
+	task := "task:\n  id: 37\n  processor: sh\n  processors: [dash]\n  input: stdin\n  time_limit: 200ms\n\ntests:\n  - generator:\n      path: gen.sh\n      processor: sh\n\nsolutions:\n  - path: sum.sh\n    processor: sh\n"
+	files := map[string]string{
+		"sum.yml": task,
+		"gen.sh":  "echo $1; echo $1; echo 0\n",
+		"sum.sh":  "read a; read b || exit 1; echo $((a + b))\n",
+		"slow.sh": "read a; read b; if [ \"$a\" -gt 2 ]; then while :; do :; done; fi; echo $((a + b))\n",
+	}
+
+	inTaskDir(t, serve, files, func(dir string) {
+		config := Config{}
+		taskConfig, err := config.FindTaskConfigById(37)
+		if err != nil {
+			t.Fatal(err)
+		}
+		appConfig, err := config.ApplicationConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Inputs shrunk to a single line are discarded, the reference
+		// solution failing on them.
+		options := StressOptions{Generator: *taskConfig.StressGenerator(), Iterations: 5, Seed: 1}
+		report, err := Stress(*taskConfig, *appConfig, appConfig.Processors[0], filepath.Join(dir, TasksDir, "slow.sh"), options)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Counterexample == nil || report.Counterexample.Input != "3\n3\n" || report.Counterexample.Error != ErrTimeLimitExceeded.Error() {
+			t.Errorf("%+v", report.Counterexample)
+		}
+
+		wrong := []byte("read a; read b; echo $((a + b + 1))\n")
+		if report, err = StressTask(37, wrong, "dash", 5, 1); err != nil || report.Counterexample == nil || report.Counterexample.Input != "1\n1\n" {
+			t.Errorf("%+v %v", report, err)
+		}
+		if _, err = StressTask(37, wrong, "other", 5, 1); err == nil {
+			t.Error("stressed with a processor not allowed for the task")
+		}
+	})
+}
+
+func TestCalibrateTimeLimits(t *testing.T) {
+	serve := "processors:\n  - name: sh\n    path: /bin/sh\n  - name: slowsh\n    path: /bin/sh\n    time_multiplier: 3\n  - name: other\n    path: /bin/sh\ncalibration:\n  runs: 1\n"
+	task := "task:\n  id: 38\n  processor: sh\n  processors: [slowsh]\n\ntests:\n  - input: \"2\"\n    output: \"4\"\n\nsolutions:\n  - path: double.sh\n    processor: sh\n"
+
+	inTaskDir(t, serve, map[string]string{"double.yml": task, "double.sh": "echo $(($1 * 2))\n"}, func(dir string) {
+		config := Config{}
+		taskConfig, err := config.FindTaskConfigById(38)
+		if err != nil {
+			t.Fatal(err)
+		}
+		appConfig, err := config.ApplicationConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		limits, _, err := CalibrateTimeLimits(*taskConfig, *appConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, exists := limits["other"]; exists || len(limits) != 2 || limits["slowsh"] < limits["sh"] {
+			t.Errorf("%v", limits)
+		}
+
+		taskConfig.Task.Type = TaskTypeSQL
+		if _, _, err = CalibrateTimeLimits(*taskConfig, *appConfig); err == nil {
+			t.Error("sql task calibrated")
+		}
+	})
 }
@@ -20,38 +20,81 @@
 package coderator
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
 	"os/exec"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 type Comparator interface {
 	Compare(a string, b string) bool
 }
 
-type ExternalComparator struct {
-	Command string
-	Comparator
+type ComparatorConfig struct {
+	Name   string
+	Values map[string]string `yaml:",omitempty"`
 }
 
-func (c ExternalComparator) Compare(a string, b string) bool {
-	cmd := exec.Command(c.Command, a, b)
-	if err := cmd.Run(); err != nil {
-		fmt.Println(errors.New("Could not run command!"))
+func (config ComparatorConfig) Comparator() Comparator {
+	switch config.Name {
+	case "", "exact":
+		return ExactComparator{}
+	case "eps":
+		accuracy, err := strconv.ParseFloat(config.Values["accuracy"], 64)
+		if err != nil {
+			return nil
+		}
+		return ApproximateComparator{Accuracy: accuracy}
+	case "external":
+		if config.Values["command"] == "" {
+			return nil
+		}
+		return ExternalComparator{Command: config.Values["command"]}
+	case "json":
+		accuracy := 0.0
+		if value, ok := config.Values["accuracy"]; ok {
+			var err error
+			if accuracy, err = strconv.ParseFloat(value, 64); err != nil {
+				return nil
+			}
+		}
+		return JSONComparator{Accuracy: accuracy}
+	case "ordered":
+		return OrderedComparator{}
+	case "unordered":
+		return UnorderedComparator{}
 	}
+	return nil
+}
 
-	if err := cmd.Wait(); err != nil {
-		panic("Error while waiting!")
+// Compare compares the outputs with the configured comparator, ignoring
+// trailing whitespace on both sides.
+func (config ComparatorConfig) Compare(a string, b string) bool {
+	comparator := config.Comparator()
+	if comparator == nil {
+		fmt.Println(errors.New("Unknown comparator " + config.Name))
+		return false
 	}
+	return comparator.Compare(TrimOutput(a), TrimOutput(b))
+}
 
-	state := cmd.ProcessState
-	if state != nil {
-		return state.Success()
+type ExternalComparator struct {
+	Command string
+	Comparator
+}
+
+// Compare runs the command with both outputs as arguments, the outputs
+// being equal when the command succeeds.
+func (c ExternalComparator) Compare(a string, b string) bool {
+	err := exec.Command(c.Command, a, b).Run()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		fmt.Println(err)
 	}
-	fmt.Println(errors.New("Could not execute command!"))
-	return false
+	return err == nil
 }
 
 type ExactComparator struct {
@@ -76,3 +119,82 @@ func (c ApproximateComparator) Compare(a string, b string) bool {
 	}
 	return math.Abs(ai-bi) <= c.Accuracy
 }
+
+// JSONComparator compares JSON values regardless of their formatting and
+// the order of object keys, numbers being equal within the accuracy.
+type JSONComparator struct {
+	Accuracy float64
+	Comparator
+}
+
+func (c JSONComparator) Compare(a string, b string) bool {
+	var av, bv interface{}
+	if json.Unmarshal([]byte(a), &av) != nil || json.Unmarshal([]byte(b), &bv) != nil {
+		return false
+	}
+	return c.equal(av, bv)
+}
+
+func (c JSONComparator) equal(a interface{}, b interface{}) bool {
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		return ok && math.Abs(a-b) <= c.Accuracy
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !c.equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, exists := b[key]
+			if !exists || !c.equal(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+// OrderedComparator compares result sets row by row.
+type OrderedComparator struct {
+	Comparator
+}
+
+func (c OrderedComparator) Compare(a string, b string) bool {
+	return strings.Join(resultRows(a), "\n") == strings.Join(resultRows(b), "\n")
+}
+
+// UnorderedComparator compares result sets regardless of the order of
+// their rows.
+type UnorderedComparator struct {
+	Comparator
+}
+
+func (c UnorderedComparator) Compare(a string, b string) bool {
+	ar, br := resultRows(a), resultRows(b)
+	sort.Strings(ar)
+	sort.Strings(br)
+	return strings.Join(ar, "\n") == strings.Join(br, "\n")
+}
+
+func resultRows(result string) []string {
+	rows := make([]string, 0)
+	for _, row := range strings.Split(strings.TrimSpace(result), "\n") {
+		if row = strings.TrimSpace(row); row != "" {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
@@ -24,30 +24,65 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v2"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
 )
 
 // FIXME: Performance of concurrent access heavily depends on disk I/O?
 
 const (
-	TasksDir  = "tasks"
-	Extension = ".yml"
+	TasksDir    = "tasks"
+	ContestsDir = "contests"
+	Extension   = ".yml"
 )
 
 type Config struct {
 }
 
 type TaskConfig struct {
-	Task  Task
-	Tests []Test
+	Task      Task
+	Tests     []Test
+	Validator *Program
+	Solutions []Solution
+	Suite     *TestSuite
+	Mutation  *Mutation
+	Service   *Service
+
+	dir  string
+	path string
+}
+
+type ContestConfig struct {
+	Contest Contest
+}
+
+type AdminConfig struct {
+	Token string
+}
+
+type UserConfig struct {
+	Name  string
+	Token string
 }
 
 type ApplicationConfig struct {
-	Processors []LanguageProcessor
+	Processors  []LanguageProcessor
+	Limits      Limits
+	Admin       AdminConfig
+	Users       []UserConfig
+	Calibration CalibrationConfig
+	WorkDir     string
+	Runners     []RunnerConfig
 }
 
 func (config Config) ApplicationConfig() (*ApplicationConfig, error) {
-	data, err := ioutil.ReadFile("serve" + Extension)
+	return LoadApplicationConfig("serve" + Extension)
+}
+
+func LoadApplicationConfig(path string) (*ApplicationConfig, error) {
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -67,30 +102,72 @@ func (config Config) filenameForTask(task Task) string {
 	return extension
 }
 
-func (config Config) AllTasks() ([]Task, error) {
+// LoadTaskConfig reads the task file at the path. Program paths of the task
+// are resolved relative to the directory of the file.
+func LoadTaskConfig(path string) (*TaskConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	taskConfig := TaskConfig{}
+	if err = yaml.Unmarshal(data, &taskConfig); err != nil {
+		return nil, err
+	}
+	taskConfig.dir = filepath.Dir(path)
+	taskConfig.path = path
+	return &taskConfig, nil
+}
+
+func (config Config) taskConfigs() ([]TaskConfig, error) {
 	files, err := ioutil.ReadDir(TasksDir)
 	if err != nil {
 		return nil, err
 	}
 
-	tasks := make([]Task, 0)
+	taskConfigs := make([]TaskConfig, 0)
 	for _, file := range files {
-		taskConfig := TaskConfig{}
-		data, err := ioutil.ReadFile(TasksDir + "/" + file.Name())
-		if err != nil {
-			fmt.Println(err)
+		if file.IsDir() || filepath.Ext(file.Name()) != Extension {
 			continue
 		}
 
-		if err = yaml.Unmarshal(data, &taskConfig); err != nil {
+		taskConfig, err := LoadTaskConfig(filepath.Join(TasksDir, file.Name()))
+		if err != nil {
 			fmt.Println(err)
 			continue
 		}
+		taskConfigs = append(taskConfigs, *taskConfig)
+	}
+	return taskConfigs, nil
+}
+
+func (config Config) AllTasks() ([]Task, error) {
+	taskConfigs, err := config.taskConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]Task, 0)
+	for _, taskConfig := range taskConfigs {
 		tasks = append(tasks, taskConfig.Task)
 	}
 	return tasks, nil
 }
 
+func (config Config) FindTaskConfigById(id uint64) (*TaskConfig, error) {
+	taskConfigs, err := config.taskConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, taskConfig := range taskConfigs {
+		if taskConfig.Task.Id == id {
+			return &taskConfig, nil
+		}
+	}
+	return nil, errors.New("No task found")
+}
+
 func (config Config) FindTaskById(id uint64) (*Task, error) {
 	tasks, err := config.AllTasks()
 	if err != nil {
@@ -109,31 +186,235 @@ func (config Config) AddTask(task Task) {
 	panic(errors.New("Not implemented"))
 }
 
+// FindTestsByTaskId returns the tests of the task without running any
+// program. Generated tests missing from the cache, such as after their
+// generator or the reference solution changed, are generated in the
+// background and found once they are ready.
 func (config Config) FindTestsByTaskId(id uint64) ([]Test, error) {
-	files, err := ioutil.ReadDir(TasksDir)
+	taskConfig, err := config.FindTaskConfigById(id)
+	if err != nil {
+		return nil, errors.New("No tests found")
+	}
+	tests, err := taskConfig.CachedTests()
+	if err != nil {
+		go config.refreshTests(*taskConfig)
+		return nil, err
+	}
+	return tests, nil
+}
+
+// AddTest appends the test to the list of tests in the task file unless a
+// test with the same input exists. The file is edited as text so that the
+// formatting of the other tests is kept.
+func (config Config) AddTest(taskId uint64, test Test) error {
+	unlock := lockTaskFile(taskId)
+	defer unlock()
+
+	taskConfig, err := config.FindTaskConfigById(taskId)
+	if err != nil {
+		return err
+	}
+	for _, existing := range taskConfig.Tests {
+		if existing.Input == test.Input && existing.Files.Input == "" && existing.Generator == nil {
+			return nil
+		}
+	}
+
+	data, err := ioutil.ReadFile(taskConfig.path)
+	if err != nil {
+		return err
+	}
+
+	item, err := yaml.Marshal([]yaml.MapSlice{{
+		{Key: "input", Value: test.Input},
+		{Key: "output", Value: test.Output},
+		{Key: "comparator", Value: test.Comparator},
+	}})
+	if err != nil {
+		return err
+	}
+
+	lines := strings.SplitAfter(string(data), "\n")
+	start := -1
+	for i, line := range lines {
+		if strings.TrimRight(line, " \r\n") == "tests:" {
+			start = i
+		}
+	}
+
+	if start < 0 {
+		out := strings.TrimRight(string(data), "\n") + "\n\ntests:\n" + string(item)
+		return writeFile(taskConfig.path, []byte(out))
+	}
+
+	end := start + 1
+	indent := ""
+	for ; end < len(lines); end++ {
+		trimmed := strings.TrimLeft(lines[end], " ")
+		if trimmed == "" || trimmed == "\n" {
+			continue
+		}
+		if len(trimmed) == len(lines[end]) && !strings.HasPrefix(trimmed, "- ") {
+			break
+		}
+		if strings.HasPrefix(trimmed, "- ") && indent == "" {
+			indent = lines[end][:len(lines[end])-len(trimmed)]
+		}
+	}
+	for end > start+1 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+
+	indented := ""
+	for _, line := range strings.SplitAfter(string(item), "\n") {
+		if line != "" {
+			indented += indent + line
+		}
+	}
+	if end > 0 && !strings.HasSuffix(lines[end-1], "\n") {
+		lines[end-1] += "\n"
+	}
+
+	out := strings.Join(lines[:end], "") + indented + strings.Join(lines[end:], "")
+	if err = yaml.Unmarshal([]byte(out), &TaskConfig{}); err != nil {
+		return errors.New("Tests of the task are not a list")
+	}
+	return writeFile(taskConfig.path, []byte(out))
+}
+
+var taskFileMutexes = make(map[uint64]*sync.Mutex)
+var taskFileMutexesMutex sync.Mutex
+
+// lockTaskFile locks the file of the task against concurrent edits and
+// returns the function unlocking it.
+func lockTaskFile(taskId uint64) func() {
+	taskFileMutexesMutex.Lock()
+	mutex := taskFileMutexes[taskId]
+	if mutex == nil {
+		mutex = &sync.Mutex{}
+		taskFileMutexes[taskId] = mutex
+	}
+	taskFileMutexesMutex.Unlock()
+
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// writeFile replaces the file by writing a temporary file next to it and
+// renaming it, so that readers and crashes never leave a partial file.
+func writeFile(path string, data []byte) error {
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+	}
+	return err
+}
+
+func (taskConfig TaskConfig) ProgramPath(program Program) string {
+	return filepath.Join(taskConfig.dir, program.Path)
+}
+
+// RunProgram runs the program of the task with the processor it is
+// written for, passing the input to its standard input.
+func (taskConfig TaskConfig) RunProgram(appConfig ApplicationConfig, program Program, input string, args ...string) (string, error) {
+	processor := appConfig.FindProcessorByName(program.Processor)
+	if processor == nil {
+		return "", errors.New("Unknown processor " + program.Processor)
+	}
+	return processor.RunFileWithInput(input, append([]string{taskConfig.ProgramPath(program)}, args...)...)
+}
+
+// RunSolution runs a solution of the task on the input, passed the way the
+// task passes inputs to solutions, through the harness of function tasks.
+func (taskConfig TaskConfig) RunSolution(appConfig ApplicationConfig, program Program, input string) (string, error) {
+	processor := appConfig.FindProcessorByName(program.Processor)
+	if processor == nil {
+		return "", errors.New("Unknown processor " + program.Processor)
+	}
+	path, cleanup, err := taskConfig.Task.PrepareSolution(*processor, taskConfig.ProgramPath(program))
+	if err != nil {
+		return "", err
+	}
+	defer cleanup()
+
+	tester := Tester{Stdin: taskConfig.Task.ReadsStdin()}
+	return tester.Execute(*processor, path, input)
+}
+
+// DefaultComparator is used for tests not listed in the task file, such as
+// the ones coming from hacks.
+func (taskConfig TaskConfig) DefaultComparator() ComparatorConfig {
+	comparator := ComparatorConfig{Name: "exact"}
+	if len(taskConfig.Tests) > 0 {
+		comparator = taskConfig.Tests[0].Comparator
+	}
+	if taskConfig.Task.Function != nil && comparator.Name == "" {
+		comparator.Name = "json"
+	}
+	return comparator
+}
+
+func (config Config) AllContests() ([]Contest, error) {
+	files, err := ioutil.ReadDir(ContestsDir)
 	if err != nil {
 		return nil, err
 	}
 
+	contests := make([]Contest, 0)
 	for _, file := range files {
-		taskConfig := TaskConfig{}
-		data, err := ioutil.ReadFile(TasksDir + "/" + file.Name())
+		contestConfig := ContestConfig{}
+		data, err := ioutil.ReadFile(ContestsDir + "/" + file.Name())
 		if err != nil {
 			fmt.Println(err)
 			continue
 		}
 
-		if err = yaml.Unmarshal(data, &taskConfig); err != nil {
+		if err = yaml.Unmarshal(data, &contestConfig); err != nil {
 			fmt.Println(err)
 			continue
 		}
+		contests = append(contests, contestConfig.Contest)
+	}
+	return contests, nil
+}
 
-		if taskConfig.Task.Id == id {
-			tests := taskConfig.Tests
-			return tests, nil
+func (config Config) FindContestById(id uint64) (*Contest, error) {
+	contests, err := config.AllContests()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, contest := range contests {
+		if contest.Id == id {
+			return &contest, nil
 		}
 	}
-	return nil, errors.New("No tests found")
+	return nil, errors.New("No contest found")
+}
+
+func (config ApplicationConfig) FindUserByToken(token string) *UserConfig {
+	if token == "" {
+		return nil
+	}
+	for _, user := range config.Users {
+		if user.Token == token {
+			return &user
+		}
+	}
+	return nil
 }
 
 func (config ApplicationConfig) FindProcessorByName(name string) *LanguageProcessor {
@@ -0,0 +1,217 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ScoringICPC = "icpc"
+	ScoringIOI  = "ioi"
+)
+
+const (
+	DefaultPenalty = 20 * time.Minute
+	MaxTaskPoints  = 100
+)
+
+type HackingPhase struct {
+	Start time.Time
+	End   time.Time
+}
+
+type Contest struct {
+	Id      uint64
+	Title   string
+	Tasks   []uint64
+	Start   time.Time
+	End     time.Time
+	Freeze  time.Duration
+	Scoring string
+	Penalty time.Duration
+	Hacking HackingPhase
+}
+
+var participants = make(map[uint64]map[string]bool)
+var participantsMutex sync.RWMutex
+
+func (contest Contest) HasTask(taskId uint64) bool {
+	for _, id := range contest.Tasks {
+		if id == taskId {
+			return true
+		}
+	}
+	return false
+}
+
+func (contest Contest) IsRunning(now time.Time) bool {
+	return !now.Before(contest.Start) && now.Before(contest.End)
+}
+
+func (contest Contest) IsHackingOpen(now time.Time) bool {
+	hacking := contest.Hacking
+	return !hacking.Start.IsZero() && !now.Before(hacking.Start) && now.Before(hacking.End)
+}
+
+// IsFrozen reports whether the scoreboard stops showing new results.
+func (contest Contest) IsFrozen(now time.Time) bool {
+	return contest.Freeze > 0 && contest.IsRunning(now) && !now.Before(contest.End.Add(-contest.Freeze))
+}
+
+func Register(contest Contest, user string) {
+	participantsMutex.Lock()
+	defer participantsMutex.Unlock()
+
+	if participants[contest.Id] == nil {
+		participants[contest.Id] = make(map[string]bool)
+	}
+	participants[contest.Id][user] = true
+}
+
+func IsRegistered(contest Contest, user string) bool {
+	participantsMutex.RLock()
+	defer participantsMutex.RUnlock()
+	return participants[contest.Id][user]
+}
+
+func Participants(contest Contest) []string {
+	participantsMutex.RLock()
+	defer participantsMutex.RUnlock()
+
+	users := make([]string, 0, len(participants[contest.Id]))
+	for user := range participants[contest.Id] {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	return users
+}
+
+type ScoreboardTask struct {
+	TaskId   uint64
+	Attempts int
+	Pending  int
+	Solved   bool
+	Time     int64
+	Points   float64
+}
+
+type ScoreboardRow struct {
+	Rank    int
+	User    string
+	Solved  int
+	Penalty int64
+	Points  float64
+	Tasks   []ScoreboardTask
+}
+
+type Scoreboard struct {
+	ContestId uint64
+	Scoring   string
+	Frozen    bool
+	Rows      []ScoreboardRow
+}
+
+// ComputeScoreboard ranks the participants by their submissions to the
+// contest tasks. Submissions made at or after the visibility deadline are
+// shown as pending only, which is how the scoreboard is frozen.
+func ComputeScoreboard(contest Contest, users []string, submissions []Submission, visible time.Time) Scoreboard {
+	penalty := contest.Penalty
+	if penalty == 0 {
+		penalty = DefaultPenalty
+	}
+
+	rows := make([]ScoreboardRow, 0, len(users))
+	for _, user := range users {
+		row := ScoreboardRow{User: user}
+		for _, taskId := range contest.Tasks {
+			cell := ScoreboardTask{TaskId: taskId}
+			for _, submission := range submissions {
+				if submission.User != user || submission.TaskId != taskId || !contest.IsRunning(submission.Time) {
+					continue
+				}
+				if !submission.Time.Before(visible) || !HasVerificationCompleted(submission) {
+					cell.Pending++
+					continue
+				}
+
+				if points := submissionPoints(submission); points > cell.Points {
+					cell.Points = points
+				}
+				if cell.Solved || submission.Result == InternalError || submission.Result == BadSource {
+					continue
+				}
+
+				cell.Attempts++
+				if submission.Result == Success {
+					cell.Solved = true
+					cell.Time = int64(submission.Time.Sub(contest.Start) / time.Minute)
+				}
+			}
+
+			if cell.Solved {
+				row.Solved++
+				row.Penalty += cell.Time + int64(cell.Attempts-1)*int64(penalty/time.Minute)
+			}
+			row.Points += cell.Points
+			row.Tasks = append(row.Tasks, cell)
+		}
+		rows = append(rows, row)
+	}
+
+	less := func(a, b ScoreboardRow) bool {
+		if contest.Scoring == ScoringIOI {
+			return a.Points > b.Points
+		}
+		return a.Solved > b.Solved || a.Solved == b.Solved && a.Penalty < b.Penalty
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return less(rows[i], rows[j])
+	})
+	for i := range rows {
+		rows[i].Rank = i + 1
+		if i > 0 && !less(rows[i-1], rows[i]) {
+			rows[i].Rank = rows[i-1].Rank
+		}
+	}
+
+	return Scoreboard{
+		ContestId: contest.Id,
+		Scoring:   contest.Scoring,
+		Rows:      rows,
+	}
+}
+
+func submissionPoints(submission Submission) float64 {
+	if len(submission.Results) == 0 {
+		return 0
+	}
+
+	passed := 0
+	for _, result := range submission.Results {
+		if result {
+			passed++
+		}
+	}
+	return MaxTaskPoints * float64(passed) / float64(len(submission.Results))
+}
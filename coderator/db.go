@@ -104,3 +104,10 @@ func (db *Database) FindTestsByTaskId(taskId uint64) ([]Test, error) {
 	}
 	return tests, nil
 }
+
+func (db *Database) AddTest(taskId uint64, test Test) error {
+	statement := "INSERT INTO tests (taskId, input, output) SELECT $1, $2, $3 " +
+		"WHERE NOT EXISTS (SELECT 1 FROM tests WHERE taskId = $1 AND input = $2)"
+	_, err := db.Exec(statement, taskId, test.Input, test.Output)
+	return err
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"bufio"
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// DetectProcessor guesses the processor of the source among the given ones
+// from the extension of the file name, then from the interpreter named in
+// the shebang line and finally from the keywords of the processors found
+// in the source. Each step only narrows down the processors left by the
+// previous one, and nil is returned if more than one processor is left.
+func DetectProcessor(processors []LanguageProcessor, filename string, source []byte) *LanguageProcessor {
+	candidates := processors
+
+	extension := strings.ToLower(filepath.Ext(filename))
+	if extension != "" {
+		candidates = narrow(candidates, func(processor LanguageProcessor) int {
+			for _, known := range processor.Extensions {
+				if strings.ToLower(known) == extension {
+					return 1
+				}
+			}
+			return 0
+		})
+	}
+
+	if interpreter := shebangInterpreter(source); interpreter != "" {
+		candidates = narrow(candidates, func(processor LanguageProcessor) int {
+			name := processor.Name + processor.Version
+			if name == interpreter {
+				return 3
+			}
+			if strings.HasPrefix(name, interpreter) {
+				return 2
+			}
+			if strings.HasPrefix(interpreter, processor.Name) {
+				return 1
+			}
+			return 0
+		})
+	}
+
+	candidates = narrow(candidates, func(processor LanguageProcessor) int {
+		score := 0
+		for _, keyword := range processor.Keywords {
+			score += bytes.Count(source, []byte(keyword))
+		}
+		return score
+	})
+
+	if len(candidates) != 1 {
+		return nil
+	}
+	return &candidates[0]
+}
+
+// narrow keeps the processors with the highest positive score, or all of
+// them if none scores.
+func narrow(processors []LanguageProcessor, score func(processor LanguageProcessor) int) []LanguageProcessor {
+	best := 0
+	kept := make([]LanguageProcessor, 0)
+	for _, processor := range processors {
+		value := score(processor)
+		if value > best {
+			best = value
+			kept = kept[:0]
+		}
+		if value == best && value > 0 {
+			kept = append(kept, processor)
+		}
+	}
+	if best == 0 {
+		return processors
+	}
+	return kept
+}
+
+// shebangInterpreter returns the name of the interpreter in the shebang line
+// of the source, skipping env.
+func shebangInterpreter(source []byte) string {
+	line, _ := bufio.NewReader(bytes.NewReader(source)).ReadString('\n')
+	if !strings.HasPrefix(line, "#!") {
+		return ""
+	}
+
+	fields := strings.Fields(line[2:])
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = filepath.Base(fields[1])
+	}
+	return interpreter
+}
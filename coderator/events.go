@@ -0,0 +1,89 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"sync"
+)
+
+const (
+	EventClarification = "clarification"
+	EventAnnouncement  = "announcement"
+)
+
+// Event is delivered to the subscribers of a contest. Events addressed to a
+// user are not delivered to other participants.
+type Event struct {
+	Type string
+	User string
+	Data interface{}
+}
+
+type subscriber struct {
+	user   string
+	events chan Event
+}
+
+type Broker struct {
+	mutex       sync.Mutex
+	subscribers map[uint64]map[*subscriber]bool
+}
+
+var events = NewBroker()
+
+func NewBroker() *Broker {
+	return &Broker{subscribers: make(map[uint64]map[*subscriber]bool)}
+}
+
+// Subscribe returns a channel of the contest events visible to the user and
+// a function to cancel the subscription. An empty user receives all events.
+func (b *Broker) Subscribe(contestId uint64, user string) (<-chan Event, func()) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	s := &subscriber{user: user, events: make(chan Event, 16)}
+	if b.subscribers[contestId] == nil {
+		b.subscribers[contestId] = make(map[*subscriber]bool)
+	}
+	b.subscribers[contestId][s] = true
+
+	return s.events, func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		delete(b.subscribers[contestId], s)
+	}
+}
+
+// Publish sends the event without blocking, dropping it for subscribers
+// that do not keep up.
+func (b *Broker) Publish(contestId uint64, event Event) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for s := range b.subscribers[contestId] {
+		if event.User != "" && s.user != "" && s.user != event.User {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const (
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeString = "string"
+	TypeBool   = "bool"
+	ListPrefix = "[]"
+)
+
+type Param struct {
+	Name string
+	Type string
+}
+
+// Function is the signature of the function to implement in a function
+// task. Its tests list typed arguments instead of an input, the arguments
+// are passed to the program as a JSON array on the standard input, and the
+// result is expected as JSON on the standard output. The harness template
+// of the processor turns the submitted function into such a program.
+type Function struct {
+	Name   string
+	Params []Param
+	Result string
+}
+
+// ValidType reports whether the type is one of the basic types or a list
+// of them, such as []int.
+func ValidType(typ string) bool {
+	switch strings.TrimPrefix(typ, ListPrefix) {
+	case TypeInt, TypeFloat, TypeString, TypeBool:
+		return true
+	}
+	return false
+}
+
+// CheckValue reports whether the value decoded from the task file has the
+// type.
+func CheckValue(typ string, value interface{}) bool {
+	if strings.HasPrefix(typ, ListPrefix) {
+		list, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range list {
+			if !CheckValue(strings.TrimPrefix(typ, ListPrefix), item) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch value.(type) {
+	case int, int64, uint64:
+		return typ == TypeInt || typ == TypeFloat
+	case float64:
+		return typ == TypeFloat
+	case string:
+		return typ == TypeString
+	case bool:
+		return typ == TypeBool
+	}
+	return false
+}
+
+// EncodeJSON encodes the value decoded from the task file as JSON.
+func EncodeJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(jsonValue(value))
+	return string(data), err
+}
+
+// jsonValue replaces the maps decoded by yaml, which have interface keys,
+// with maps encodable as JSON.
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(value))
+		for key, item := range value {
+			object[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return object
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			list[i] = jsonValue(item)
+		}
+		return list
+	}
+	return value
+}
+
+type harness struct {
+	Source   string
+	Function Function
+	Dir      string
+}
+
+// Harness wraps the source implementing the function into a program using
+// the harness template of the processor. Dir is the directory of the
+// original source, for the harness to find the modules next to it.
+func (processor LanguageProcessor) Harness(function Function, source []byte, dir string) ([]byte, error) {
+	if processor.HarnessTemplate == "" {
+		return nil, errors.New("No harness template for " + processor.Name + processor.Version)
+	}
+
+	tmpl, err := template.ParseFiles(processor.HarnessTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	err = tmpl.Execute(buffer, harness{Source: string(source), Function: function, Dir: dir})
+	return buffer.Bytes(), err
+}
+
+// Starter returns the starter code of the task for the processor, either
+// given in the task file or generated from the function signature by the
+// starter template of the processor. An empty string means there is none.
+func (task Task) Starter(processor LanguageProcessor) (string, error) {
+	if code, ok := task.Templates[processor.Name+processor.Version]; ok {
+		return code, nil
+	}
+	if task.Function == nil || processor.StarterTemplate == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.ParseFiles(processor.StarterTemplate)
+	if err != nil {
+		return "", err
+	}
+
+	buffer := &bytes.Buffer{}
+	err = tmpl.Execute(buffer, harness{Function: *task.Function})
+	return buffer.String(), err
+}
+
+// PrepareSolution returns the program to run for the solution. For function
+// tasks it is the solution wrapped into the harness of the processor and
+// written into a new working directory, removed by the returned function.
+func (task Task) PrepareSolution(processor LanguageProcessor, path string) (string, func(), error) {
+	if task.Function == nil {
+		return path, func() {}, nil
+	}
+
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return "", nil, err
+	}
+	program, err := processor.Harness(*task.Function, source, dir)
+	if err != nil {
+		return "", nil, err
+	}
+
+	workDir, err := NewWorkDir(workDirRoot(), "harness")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() {
+		os.RemoveAll(workDir)
+	}
+
+	harnessed := filepath.Join(workDir, filepath.Base(path))
+	if err = ioutil.WriteFile(harnessed, program, 0600); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return harnessed, cleanup, nil
+}
@@ -0,0 +1,267 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheDir keeps the generated inputs and answers next to the task files.
+const CacheDir = ".cache"
+
+// TestFiles refers to the input and answer of a test stored in files next
+// to the task file.
+type TestFiles struct {
+	Input  string
+	Answer string
+}
+
+// Generator is a program whose output becomes the input of a test. The seed
+// is passed to the generator after the other arguments.
+type Generator struct {
+	Path      string
+	Processor string
+	Args      []string
+	Seed      int64
+}
+
+func (generator Generator) Program() Program {
+	return Program{Path: generator.Path, Processor: generator.Processor}
+}
+
+func (generator Generator) Arguments() []string {
+	return append(append([]string{}, generator.Args...), fmt.Sprint(generator.Seed))
+}
+
+// MaterializeTests reads the tests stored in files and runs the generators
+// so that every returned test has its input and output inline. Generated
+// inputs are checked by the input validator, and answers missing for such
+// tests are produced by the reference solution. Generated inputs and answers
+// are cached until the programs producing them change. Tests without an id
+// are numbered by their position.
+func (taskConfig TaskConfig) MaterializeTests(appConfig ApplicationConfig) ([]Test, error) {
+	return taskConfig.materializeTests(appConfig, true)
+}
+
+// CachedTests returns the tests like MaterializeTests without running any
+// program, failing for generated inputs and answers not in the cache yet.
+func (taskConfig TaskConfig) CachedTests() ([]Test, error) {
+	return taskConfig.materializeTests(ApplicationConfig{}, false)
+}
+
+// MaterializeAllTests runs MaterializeTests for every task file in the
+// directory, filling the cache before the tests are served.
+func MaterializeAllTests(dir string, appConfig ApplicationConfig) []Problem {
+	problems := make([]Problem, 0)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+Extension))
+	if err != nil {
+		return append(problems, Problem{dir, err.Error()})
+	}
+
+	for _, path := range paths {
+		taskConfig, err := LoadTaskConfig(path)
+		if err != nil {
+			problems = append(problems, Problem{path, err.Error()})
+			continue
+		}
+		if _, err = taskConfig.MaterializeTests(appConfig); err != nil {
+			problems = append(problems, Problem{path, err.Error()})
+		}
+	}
+	return problems
+}
+
+var refreshedTasks = make(map[uint64]bool)
+var refreshedTasksMutex sync.Mutex
+
+// refreshTests materializes the tests of the task unless they are being
+// materialized already.
+func (config Config) refreshTests(taskConfig TaskConfig) {
+	id := taskConfig.Task.Id
+	refreshedTasksMutex.Lock()
+	if refreshedTasks[id] {
+		refreshedTasksMutex.Unlock()
+		return
+	}
+	refreshedTasks[id] = true
+	refreshedTasksMutex.Unlock()
+
+	defer func() {
+		refreshedTasksMutex.Lock()
+		delete(refreshedTasks, id)
+		refreshedTasksMutex.Unlock()
+	}()
+
+	appConfig, err := config.ApplicationConfig()
+	if err != nil {
+		return
+	}
+	if _, err = taskConfig.MaterializeTests(*appConfig); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func (taskConfig TaskConfig) materializeTests(appConfig ApplicationConfig, run bool) ([]Test, error) {
+	tests := make([]Test, 0, len(taskConfig.Tests))
+	for i, test := range taskConfig.Tests {
+		materialized, err := taskConfig.materializeTest(appConfig, test, run)
+		if err != nil {
+			return nil, fmt.Errorf("test %d: %s", i+1, err)
+		}
+		materialized.Id = TestId(test, i)
+		tests = append(tests, materialized)
+	}
+	return tests, nil
+}
+
+func (taskConfig TaskConfig) materializeTest(appConfig ApplicationConfig, test Test, run bool) (Test, error) {
+	external := test.Files.Input != "" || test.Generator != nil
+
+	if test.Args != nil {
+		input, err := EncodeJSON(test.Args)
+		if err != nil {
+			return test, err
+		}
+		output, err := EncodeJSON(test.Result)
+		if err != nil {
+			return test, err
+		}
+		test.Input, test.Output = input, output
+		if test.Comparator.Name == "" {
+			test.Comparator.Name = "json"
+		}
+	}
+
+	if test.Files.Input != "" {
+		data, err := ioutil.ReadFile(filepath.Join(taskConfig.dir, test.Files.Input))
+		if err != nil {
+			return test, err
+		}
+		test.Input = string(data)
+	}
+
+	if test.Generator != nil {
+		generator := *test.Generator
+		key, err := taskConfig.cacheKey(generator.Program(), generator.Arguments()...)
+		if err != nil {
+			return test, err
+		}
+
+		test.Input, err = taskConfig.cached(key+".in", run, func() (string, error) {
+			input, err := taskConfig.RunProgram(appConfig, generator.Program(), "", generator.Arguments()...)
+			if err != nil {
+				return "", err
+			}
+
+			violation, err := taskConfig.ValidateInput(appConfig, input)
+			if err == nil && violation != "" {
+				err = errors.New("generated input is invalid: " + violation)
+			}
+			return input, err
+		})
+		if err != nil {
+			return test, err
+		}
+	}
+
+	if test.Files.Answer != "" {
+		data, err := ioutil.ReadFile(filepath.Join(taskConfig.dir, test.Files.Answer))
+		if err != nil {
+			return test, err
+		}
+		test.Output = TrimOutput(string(data))
+	} else if external && test.Output == "" {
+		reference := taskConfig.ReferenceSolution()
+		if reference == nil {
+			return test, errors.New("no answer and no reference solution")
+		}
+
+		key, err := taskConfig.cacheKey(*reference, test.Input, fmt.Sprint(taskConfig.Task.ReadsStdin()))
+		if err != nil {
+			return test, err
+		}
+
+		input := test.Input
+		test.Output, err = taskConfig.cached(key+".ans", run, func() (string, error) {
+			output, err := taskConfig.RunSolution(appConfig, *reference, input)
+			return TrimOutput(output), err
+		})
+		if err != nil {
+			return test, err
+		}
+	}
+
+	test.Files = TestFiles{}
+	test.Generator = nil
+	test.Args = nil
+	test.Result = nil
+	return test, nil
+}
+
+// cacheKey hashes the source of the program together with the processor and
+// the other parts of its invocation.
+func (taskConfig TaskConfig) cacheKey(program Program, parts ...string) (string, error) {
+	source, err := ioutil.ReadFile(taskConfig.ProgramPath(program))
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	hash.Write(source)
+	hash.Write([]byte{0})
+	hash.Write([]byte(program.Processor))
+	for _, part := range parts {
+		hash.Write([]byte{0})
+		hash.Write([]byte(part))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func (taskConfig TaskConfig) cached(name string, run bool, produce func() (string, error)) (string, error) {
+	dir := filepath.Join(taskConfig.dir, CacheDir, fmt.Sprint(taskConfig.Task.Id))
+	path := filepath.Join(dir, name)
+	if data, err := ioutil.ReadFile(path); err == nil {
+		return string(data), nil
+	}
+	if !run {
+		return "", errors.New("generated tests are not in the cache")
+	}
+
+	content, err := produce()
+	if err != nil {
+		return "", err
+	}
+
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err = writeFile(path, []byte(content)); err != nil {
+		return "", err
+	}
+	return content, nil
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	HackSuccessful   = "successful"
+	HackUnsuccessful = "unsuccessful"
+	HackInvalidInput = "invalid input"
+)
+
+type Hack struct {
+	Id           uint64
+	ContestId    uint64
+	SubmissionId uint64
+	TaskId       uint64
+	Hacker       string
+	Defender     string
+	Input        string
+	Verdict      string
+	Message      string
+	Time         time.Time
+}
+
+var hacks = make([]Hack, 0)
+var hacksMutex sync.RWMutex
+
+// RunHack checks the hack input with the task validator and runs both the
+// target submission and the reference solution on it. When their outputs
+// differ the input becomes a new test of the task and the accepted
+// submissions of the task are judged again.
+func RunHack(contest Contest, target Submission, hacker string, input string) (*Hack, error) {
+	hack := Hack{
+		ContestId:    contest.Id,
+		SubmissionId: target.Id,
+		TaskId:       target.TaskId,
+		Hacker:       hacker,
+		Defender:     target.User,
+		Input:        input,
+		Time:         time.Now(),
+	}
+
+	config := Config{}
+	appConfig, err := config.ApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	taskConfig, err := config.FindTaskConfigById(target.TaskId)
+	if err != nil {
+		return nil, err
+	}
+	reference := taskConfig.ReferenceSolution()
+	if reference == nil {
+		return nil, errors.New("The task has no reference solution")
+	}
+
+	violation, err := taskConfig.ValidateInput(*appConfig, input)
+	if err != nil {
+		return nil, err
+	}
+	if violation != "" {
+		hack.Verdict = HackInvalidInput
+		hack.Message = violation
+		return saveHack(hack), nil
+	}
+
+	expected, err := taskConfig.RunSolution(*appConfig, *reference, input)
+	if err != nil {
+		return nil, err
+	}
+
+	processor := appConfig.FindProcessorByName(target.Processor)
+	if processor == nil {
+		return nil, errors.New("Unknown processor " + target.Processor)
+	}
+
+	test := Test{Input: input, Output: TrimOutput(expected), Comparator: taskConfig.DefaultComparator()}
+	result, err := runSource(*processor, taskConfig.Task, target, test)
+	if err != nil {
+		return nil, err
+	}
+	if result.Successful {
+		hack.Verdict = HackUnsuccessful
+		return saveHack(hack), nil
+	}
+
+	hack.Verdict = HackSuccessful
+	hack.Message = VerdictWrongAnswer
+	if result.Error != nil {
+		hack.Message = result.Error.Error()
+	}
+
+	if err = database.AddTest(taskConfig.Task.Id, test); err != nil {
+		return nil, err
+	}
+
+	accepted := make([]Submission, 0)
+	for _, submission := range AllSubmissions() {
+		if submission.TaskId == target.TaskId && submission.Result == Success {
+			accepted = append(accepted, submission)
+		}
+	}
+	RejudgeAll(accepted)
+	return saveHack(hack), nil
+}
+
+// runSource runs the submission on the test within the limits of the task.
+// Failures of the submission itself, such as exceeding a limit or exiting
+// with an error, are part of the result, while failures to run it at all
+// are returned as errors.
+func runSource(processor LanguageProcessor, task Task, submission Submission, test Test) (TestResult, error) {
+	dir, err := NewWorkDir(workDirRoot(), "hack")
+	if err != nil {
+		return TestResult{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	entry, err := WriteSourceFiles(dir, submission.Files, submission.Entry)
+	if err != nil {
+		return TestResult{}, err
+	}
+	if _, err = os.Stat(entry); err != nil {
+		return TestResult{}, err
+	}
+	program, cleanup, err := task.PrepareSolution(processor, entry)
+	if err != nil {
+		return TestResult{}, err
+	}
+	defer cleanup()
+
+	result := task.Tester(processor).Run(processor, program, test)
+	if _, exited := result.Error.(*exec.ExitError); result.Error != nil && !exited && result.Error != ErrTimeLimitExceeded {
+		return result, result.Error
+	}
+	return result, nil
+}
+
+func saveHack(hack Hack) *Hack {
+	hacksMutex.Lock()
+	defer hacksMutex.Unlock()
+
+	hack.Id = uint64(len(hacks) + 1)
+	hacks = append(hacks, hack)
+	return &hack
+}
+
+func FindHacksByContest(contest Contest) []Hack {
+	hacksMutex.RLock()
+	defer hacksMutex.RUnlock()
+
+	found := make([]Hack, 0)
+	for _, hack := range hacks {
+		if hack.ContestId == contest.Id {
+			found = append(found, hack)
+		}
+	}
+	return found
+}
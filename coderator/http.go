@@ -0,0 +1,247 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultStartupTimeout = 10 * time.Second
+	DefaultRequestTimeout = 10 * time.Second
+
+	// MaxResponseSize is the size of the response bodies read from services.
+	MaxResponseSize = 1 << 20
+
+	probeInterval = 50 * time.Millisecond
+)
+
+// Service describes how the solutions of an HTTP task are started. The
+// solutions listen on the loopback port given in the PORT environment
+// variable and are ready once a GET request of the readiness path gets any
+// response, or once the port accepts connections if there is no such path.
+type Service struct {
+	Ready   string
+	Startup time.Duration
+}
+
+// HTTPRequest is the request of an HTTP test.
+type HTTPRequest struct {
+	Method  string
+	Path    string
+	Headers map[string]string `yaml:",omitempty"`
+	Body    string            `yaml:",omitempty"`
+}
+
+// HTTPResponse is the expected response of an HTTP test. Zero status, the
+// headers not listed and an empty body are not checked.
+type HTTPResponse struct {
+	Status  int
+	Headers map[string]string `yaml:",omitempty"`
+	Body    string            `yaml:",omitempty"`
+}
+
+// HTTPJudge starts the solution as a service and sends it the requests of
+// the tests one by one. Response bodies are compared as JSON unless the
+// expected body is not JSON or the test names another comparator.
+type HTTPJudge struct {
+}
+
+func (judge HTTPJudge) Run(taskConfig TaskConfig, appConfig ApplicationConfig, processor LanguageProcessor, path string) ([]TestResult, error) {
+	service := Service{}
+	if taskConfig.Service != nil {
+		service = *taskConfig.Service
+	}
+	if service.Startup <= 0 {
+		service.Startup = DefaultStartupTimeout
+	}
+
+	limits := taskConfig.Task.Limits(processor)
+	client := http.Client{Timeout: limits.Time}
+	if client.Timeout <= 0 {
+		client.Timeout = DefaultRequestTimeout
+	}
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	port, err := freePort()
+	if err != nil {
+		return nil, err
+	}
+	address := fmt.Sprintf("127.0.0.1:%d", port)
+
+	log := &cappedBuffer{max: MaxResponseSize}
+	env := []string{fmt.Sprint("PORT=", port), "HOST=127.0.0.1"}
+	cmd, err := processor.Start(filepath.Dir(path), limits, env, log, path)
+	if err != nil {
+		return nil, err
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	// The service runs in a process group of its own, killed as a whole
+	// along with the processes it started.
+	stop := func() {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		<-exited
+	}
+
+	if err = waitForService(address, service, exited); err != nil {
+		stop()
+		return []TestResult{{Name: "startup", Error: err, Output: strings.TrimSpace(log.String())}}, nil
+	}
+	defer stop()
+
+	results := make([]TestResult, 0, len(taskConfig.Tests))
+	for _, test := range taskConfig.Tests {
+		if test.Request == nil {
+			return nil, errors.New("Test without a request")
+		}
+		results = append(results, runHTTPTest(client, address, test))
+	}
+	return results, nil
+}
+
+// cappedBuffer keeps the first max bytes written to it.
+type cappedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (buffer *cappedBuffer) Write(p []byte) (int, error) {
+	if room := buffer.max - buffer.Len(); room < len(p) {
+		if room > 0 {
+			buffer.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return buffer.Buffer.Write(p)
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+func waitForService(address string, service Service, exited chan error) error {
+	deadline := time.Now().Add(service.Startup)
+	client := http.Client{Timeout: probeInterval * 10}
+	for time.Now().Before(deadline) {
+		select {
+		case err := <-exited:
+			exited <- err
+			return fmt.Errorf("The service exited on startup: %v", err)
+		default:
+		}
+
+		if service.Ready == "" {
+			if conn, err := net.DialTimeout("tcp", address, probeInterval); err == nil {
+				conn.Close()
+				return nil
+			}
+		} else if response, err := client.Get("http://" + address + service.Ready); err == nil {
+			response.Body.Close()
+			return nil
+		}
+		time.Sleep(probeInterval)
+	}
+	return errors.New("The service is not ready after " + service.Startup.String())
+}
+
+func runHTTPTest(client http.Client, address string, test Test) TestResult {
+	request := *test.Request
+	method := request.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	result := TestResult{Name: method + " " + request.Path}
+
+	httpRequest, err := http.NewRequest(method, "http://"+address+request.Path, strings.NewReader(request.Body))
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	for name, value := range request.Headers {
+		httpRequest.Header.Set(name, value)
+	}
+
+	response, err := client.Do(httpRequest)
+	if err != nil {
+		result.Error = err
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			result.Error = ErrTimeLimitExceeded
+		}
+		return result
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, MaxResponseSize))
+	if err != nil {
+		result.Error = err
+		return result
+	}
+	result.Output = strings.TrimSpace(fmt.Sprintf("%d %s", response.StatusCode, body))
+
+	expected := test.Response
+	if expected == nil {
+		result.Successful = true
+		return result
+	}
+	if expected.Status != 0 && expected.Status != response.StatusCode {
+		return result
+	}
+	for name, value := range expected.Headers {
+		if response.Header.Get(name) != value {
+			result.Output = fmt.Sprintf("%s: %s\n%s", name, response.Header.Get(name), result.Output)
+			return result
+		}
+	}
+	if expected.Body == "" {
+		result.Successful = true
+		return result
+	}
+
+	comparator := test.Comparator
+	if comparator.Name == "" {
+		comparator.Name = "json"
+		if !json.Valid([]byte(expected.Body)) {
+			comparator.Name = "exact"
+		}
+	}
+	result.Successful = comparator.Compare(strings.TrimSpace(string(body)), strings.TrimSpace(expected.Body))
+	return result
+}
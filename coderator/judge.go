@@ -0,0 +1,80 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+const (
+	TaskTypeIO       = "io"
+	TaskTypeSuite    = "test-suite"
+	TaskTypeMutation = "mutation"
+	TaskTypeSQL      = "sql"
+	TaskTypeHTTP     = "http"
+	TaskTypeOutput   = "output-only"
+)
+
+// Judge runs a solution of a task of some type and returns the results of
+// its tests.
+type Judge interface {
+	Run(taskConfig TaskConfig, appConfig ApplicationConfig, processor LanguageProcessor, path string) ([]TestResult, error)
+}
+
+// FindJudgeByTask returns the judge for the type of the task, nil for an
+// unknown type. Tasks without a type are judged by comparing the output of
+// the solution on the tests.
+func FindJudgeByTask(task Task) Judge {
+	switch task.Type {
+	case "", TaskTypeIO:
+		return IOJudge{}
+	case TaskTypeSuite:
+		return SuiteJudge{}
+	case TaskTypeMutation:
+		return MutationJudge{}
+	case TaskTypeSQL:
+		return SQLJudge{}
+	case TaskTypeHTTP:
+		return HTTPJudge{}
+	case TaskTypeOutput:
+		return OutputJudge{}
+	}
+	return nil
+}
+
+type IOJudge struct {
+}
+
+func (judge IOJudge) Run(taskConfig TaskConfig, appConfig ApplicationConfig, processor LanguageProcessor, path string) ([]TestResult, error) {
+	tests, err := taskConfig.MaterializeTests(appConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	task := taskConfig.Task
+	program, cleanup, err := task.PrepareSolution(processor, path)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	tester := task.Tester(processor)
+	results := make([]TestResult, 0, len(tests))
+	for _, test := range tests {
+		results = append(results, tester.Run(processor, program, test))
+	}
+	return results, nil
+}
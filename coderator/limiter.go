@@ -0,0 +1,150 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"sync"
+	"time"
+)
+
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+type Quota struct {
+	Attempts int
+	Cooldown time.Duration
+}
+
+type Limits struct {
+	Rate  RateLimit
+	Quota Quota
+}
+
+type RateLimiter struct {
+	limit    RateLimit
+	mutex    sync.Mutex
+	requests map[string][]time.Time
+	pruned   time.Time
+}
+
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	return &RateLimiter{
+		limit:    limit,
+		requests: make(map[string][]time.Time),
+	}
+}
+
+// Allow records a request of the client unless it exceeds the limit within
+// the sliding period. It returns the number of requests left and, when the
+// request is rejected, the time to wait before the next one is accepted.
+func (l *RateLimiter) Allow(client string, now time.Time) (int, time.Duration, bool) {
+	if l.limit.Requests <= 0 || l.limit.Period <= 0 {
+		return -1, 0, true
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.prune(now)
+
+	recent := make([]time.Time, 0, l.limit.Requests)
+	for _, request := range l.requests[client] {
+		if now.Sub(request) < l.limit.Period {
+			recent = append(recent, request)
+		}
+	}
+
+	if len(recent) >= l.limit.Requests {
+		l.requests[client] = recent
+		return 0, l.limit.Period - now.Sub(recent[0]), false
+	}
+
+	l.requests[client] = append(recent, now)
+	return l.limit.Requests - len(recent) - 1, 0, true
+}
+
+// prune forgets the clients without requests within the period, at most
+// once a period, so that the clients seen once do not pile up.
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < l.limit.Period {
+		return
+	}
+	l.pruned = now
+
+	for client, requests := range l.requests {
+		if len(requests) == 0 || now.Sub(requests[len(requests)-1]) >= l.limit.Period {
+			delete(l.requests, client)
+		}
+	}
+}
+
+// EffectiveQuota overrides the default quota with the task specific values.
+func EffectiveQuota(defaults Quota, task Task) Quota {
+	quota := defaults
+	if task.Quota.Attempts > 0 {
+		quota.Attempts = task.Quota.Attempts
+	}
+	if task.Quota.Cooldown > 0 {
+		quota.Cooldown = task.Quota.Cooldown
+	}
+	return quota
+}
+
+// CheckQuota reports how many attempts the user has left for the task and,
+// when another attempt is not allowed yet, the time to wait. A negative
+// number of attempts means the quota is unlimited.
+func CheckQuota(quota Quota, task Task, user string, now time.Time) (int, time.Duration, bool) {
+	previous := FindSubmissionsByTaskAndUser(task.Id, user)
+
+	remaining := -1
+	if quota.Attempts > 0 {
+		remaining = quota.Attempts - len(previous)
+		if remaining <= 0 {
+			return 0, 0, false
+		}
+	}
+
+	if quota.Cooldown > 0 && len(previous) > 0 {
+		elapsed := now.Sub(previous[len(previous)-1].Time)
+		if elapsed < quota.Cooldown {
+			return remaining, quota.Cooldown - elapsed, false
+		}
+	}
+	return remaining, 0, true
+}
+
+var quotaMutex sync.Mutex
+
+// SubmitWithinQuota creates the submission unless the quota of the user for
+// the task is exceeded. The check and the insert happen under one lock, so
+// concurrent submissions of the user cannot exceed the quota together. It
+// returns the attempts left before the submission, as CheckQuota does.
+func SubmitWithinQuota(quota Quota, task Task, user string, processor string, files []SourceFile, entry string, now time.Time) (*Submission, int, time.Duration) {
+	quotaMutex.Lock()
+	defer quotaMutex.Unlock()
+
+	remaining, wait, allowed := CheckQuota(quota, task, user, now)
+	if !allowed {
+		return nil, remaining, wait
+	}
+	submission := NewSubmission(task, user, processor, files, entry)
+	return &submission, remaining, 0
+}
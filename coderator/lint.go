@@ -0,0 +1,359 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type Problem struct {
+	File    string
+	Message string
+}
+
+func (problem Problem) String() string {
+	return problem.File + ": " + problem.Message
+}
+
+// LintTasks checks every task file in the directory against the task schema
+// and the processors of the application.
+func LintTasks(dir string, appConfig ApplicationConfig) []Problem {
+	problems := make([]Problem, 0)
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return append(problems, Problem{dir, err.Error()})
+	}
+
+	ids := make(map[uint64]string)
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != Extension {
+			continue
+		}
+
+		path := filepath.Join(dir, file.Name())
+		taskConfig, fileProblems := lintTaskFile(path, appConfig)
+		problems = append(problems, fileProblems...)
+		if taskConfig == nil {
+			continue
+		}
+
+		id := taskConfig.Task.Id
+		if other, exists := ids[id]; exists {
+			problems = append(problems, Problem{path, fmt.Sprintf("task id %d is already used by %s", id, other)})
+		} else if id != 0 {
+			ids[id] = path
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].File < problems[j].File
+	})
+	return problems
+}
+
+func lintTaskFile(path string, appConfig ApplicationConfig) (*TaskConfig, []Problem) {
+	problems := make([]Problem, 0)
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, Problem{path, fmt.Sprintf(format, args...)})
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		report("%s", err)
+		return nil, problems
+	}
+
+	taskConfig := TaskConfig{}
+	if err = yaml.UnmarshalStrict(data, &taskConfig); err != nil {
+		report("%s", err)
+		return nil, problems
+	}
+	taskConfig.dir = filepath.Dir(path)
+	taskConfig.path = path
+
+	task := taskConfig.Task
+	if task.Id == 0 {
+		report("task id is missing")
+	}
+	if task.Title == "" {
+		report("task title is missing")
+	}
+	if task.Text == "" {
+		report("task text is missing")
+	}
+	if task.Type == TaskTypeOutput {
+		if task.Processor != "" {
+			report("output-only task with processor %s", task.Processor)
+		}
+	} else if task.Processor == "" {
+		report("task processor is missing")
+	} else if appConfig.FindProcessorByName(task.Processor) == nil {
+		report("unknown processor %s", task.Processor)
+	}
+	for _, name := range task.Processors {
+		if name != AnyProcessor && appConfig.FindProcessorByName(name) == nil {
+			report("unknown processor %s", name)
+		}
+	}
+	if task.Input != "" && task.Input != InputArgument && task.Input != InputStdin {
+		report("unknown input %s", task.Input)
+	}
+	for name, limit := range task.TimeLimits {
+		if appConfig.FindProcessorByName(name) == nil {
+			report("time limit for unknown processor %s", name)
+		} else if limit <= 0 {
+			report("time limit for %s must be positive", name)
+		}
+	}
+
+	for name := range task.Templates {
+		if !task.AllowsProcessor(name) {
+			report("template for processor %s not allowed for the task", name)
+		}
+	}
+	if function := task.Function; function != nil {
+		if function.Name == "" {
+			report("function name is missing")
+		}
+		for _, param := range function.Params {
+			if !ValidType(param.Type) {
+				report("function parameter %s: unknown type %q", param.Name, param.Type)
+			}
+		}
+		if !ValidType(function.Result) {
+			report("function result: unknown type %q", function.Result)
+		}
+		for _, processor := range task.AllowedProcessors(appConfig) {
+			if _, err := os.Stat(processor.HarnessTemplate); processor.HarnessTemplate == "" || err != nil {
+				report("no harness template for processor %s", processor.Name+processor.Version)
+			}
+		}
+	}
+
+	switch task.Type {
+	case "", TaskTypeIO:
+		if len(taskConfig.Tests) == 0 {
+			report("task has no tests")
+		}
+	case TaskTypeSuite:
+		lintSuite(taskConfig, appConfig, report)
+	case TaskTypeMutation:
+		lintMutation(taskConfig, appConfig, report)
+	case TaskTypeHTTP:
+		if len(taskConfig.Tests) == 0 {
+			report("task has no tests")
+		}
+		for i, test := range taskConfig.Tests {
+			if test.Request == nil {
+				report("test %d: request is missing", i+1)
+			} else if !strings.HasPrefix(test.Request.Path, "/") {
+				report("test %d: request path must start with /", i+1)
+			}
+		}
+		if service := taskConfig.Service; service != nil && service.Ready != "" && !strings.HasPrefix(service.Ready, "/") {
+			report("service: readiness path must start with /")
+		}
+	case TaskTypeOutput:
+		if len(taskConfig.Tests) == 0 {
+			report("task has no tests")
+		}
+		ids := make(map[uint64]bool)
+		for i, test := range taskConfig.Tests {
+			id := TestId(test, i)
+			if ids[id] {
+				report("test %d: id %d is already used", i+1, id)
+			}
+			ids[id] = true
+			if test.Output == "" && test.Files.Answer == "" {
+				report("test %d: answer is missing", i+1)
+			}
+		}
+	case TaskTypeSQL:
+		if len(taskConfig.Tests) == 0 {
+			report("task has no tests")
+		}
+		for i, test := range taskConfig.Tests {
+			if test.Schema == "" {
+				report("test %d: schema is missing", i+1)
+			}
+			if !test.HasAnswer() && taskConfig.ReferenceSolution() == nil {
+				report("test %d: no answer and no reference solution", i+1)
+			}
+		}
+	default:
+		report("unknown task type %s", task.Type)
+	}
+	for i, test := range taskConfig.Tests {
+		if test.Comparator.Comparator() == nil {
+			report("test %d: unknown or misconfigured comparator %q", i+1, test.Comparator.Name)
+		}
+
+		inputs := 0
+		if test.Input != "" {
+			inputs++
+		}
+		if test.Args != nil {
+			inputs++
+			lintArgs(task.Function, test, func(message string) {
+				report("test %d: %s", i+1, message)
+			})
+		}
+		for _, path := range []string{test.Files.Input, test.Files.Answer} {
+			if path == "" {
+				continue
+			}
+			if _, err := os.Stat(filepath.Join(taskConfig.dir, path)); err != nil {
+				report("test %d: %s", i+1, err)
+			}
+		}
+		if test.Files.Input != "" {
+			inputs++
+		}
+		if test.Generator != nil {
+			inputs++
+			generator := test.Generator.Program()
+			if appConfig.FindProcessorByName(generator.Processor) == nil {
+				report("test %d: generator: unknown processor %s", i+1, generator.Processor)
+			}
+			if _, err := os.Stat(taskConfig.ProgramPath(generator)); err != nil {
+				report("test %d: generator: %s", i+1, err)
+			}
+		}
+
+		if inputs > 1 {
+			report("test %d: more than one input given", i+1)
+		}
+		external := test.Files.Input != "" || test.Generator != nil
+		if external && test.Output == "" && test.Files.Answer == "" && taskConfig.ReferenceSolution() == nil {
+			report("test %d: no answer and no reference solution", i+1)
+		}
+	}
+
+	type namedProgram struct {
+		name    string
+		program Program
+	}
+
+	programs := make([]namedProgram, 0)
+	if taskConfig.Validator != nil {
+		programs = append(programs, namedProgram{"validator", *taskConfig.Validator})
+	}
+	for _, solution := range taskConfig.Solutions {
+		programs = append(programs, namedProgram{"solution " + solution.Path, solution.Program()})
+
+		switch solution.ExpectedVerdict() {
+		case VerdictAccepted, VerdictRejected, VerdictWrongAnswer, VerdictRuntimeError, VerdictTimeLimit:
+		default:
+			report("solution %s: unknown verdict %s", solution.Path, solution.Verdict)
+		}
+	}
+
+	for _, entry := range programs {
+		outputs := task.Type == TaskTypeOutput && entry.program.Processor == ""
+		if !outputs && appConfig.FindProcessorByName(entry.program.Processor) == nil {
+			report("%s: unknown processor %s", entry.name, entry.program.Processor)
+		}
+		if _, err := os.Stat(taskConfig.ProgramPath(entry.program)); err != nil {
+			report("%s: %s", entry.name, err)
+		}
+	}
+	return &taskConfig, problems
+}
+
+// lintArgs checks the arguments and the result of a function task test
+// against the function signature.
+func lintArgs(function *Function, test Test, report func(message string)) {
+	if function == nil {
+		report("arguments given but the task has no function")
+		return
+	}
+	if len(test.Args) != len(function.Params) {
+		report(fmt.Sprintf("%d arguments given, %s takes %d", len(test.Args), function.Name, len(function.Params)))
+		return
+	}
+	for i, param := range function.Params {
+		if !CheckValue(param.Type, test.Args[i]) {
+			report(fmt.Sprintf("argument %s is not of type %s", param.Name, param.Type))
+		}
+	}
+	if !CheckValue(function.Result, test.Result) {
+		report("result is not of type " + function.Result)
+	}
+}
+
+func lintSuite(taskConfig TaskConfig, appConfig ApplicationConfig, report func(format string, args ...interface{})) {
+	suite := taskConfig.Suite
+	if suite == nil {
+		report("test suite is missing")
+		return
+	}
+	if _, err := os.Stat(taskConfig.ProgramPath(Program{Path: suite.Path})); err != nil {
+		report("test suite: %s", err)
+	}
+
+	lintRunner(suite.Runner, appConfig, report)
+}
+
+func lintMutation(taskConfig TaskConfig, appConfig ApplicationConfig, report func(format string, args ...interface{})) {
+	mutation := taskConfig.Mutation
+	if mutation == nil {
+		report("mutation is missing")
+		return
+	}
+	if mutation.Module == "" {
+		report("mutation: module is missing")
+	}
+	if mutation.Implementation == "" {
+		report("mutation: implementation is missing")
+	}
+	if len(mutation.Mutants) == 0 {
+		report("mutation: no mutants")
+	}
+	for _, path := range append([]string{mutation.Implementation}, mutation.Mutants...) {
+		if _, err := os.Stat(taskConfig.ProgramPath(Program{Path: path})); path != "" && err != nil {
+			report("mutation: %s", err)
+		}
+	}
+	lintRunner(mutation.Runner, appConfig, report)
+}
+
+func lintRunner(name string, appConfig ApplicationConfig, report func(format string, args ...interface{})) {
+	runner := appConfig.FindRunnerByName(name)
+	if runner == nil {
+		report("unknown runner %s", name)
+		return
+	}
+	if appConfig.FindProcessorByName(runner.Processor) == nil {
+		report("runner %s: unknown processor %s", runner.Name, runner.Processor)
+	}
+	if _, err := os.Stat(runner.Path); runner.Path != "" && err != nil {
+		report("runner %s: %s", runner.Name, err)
+	}
+	if runner.Format != FormatJUnit && runner.Format != FormatTAP {
+		report("runner %s: unknown format %s", runner.Name, runner.Format)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// Mutation describes a task whose solutions are test suites. The suites are
+// run by the runner against the correct implementation and the mutants, each
+// copied as the module the suites import.
+type Mutation struct {
+	Runner         string
+	Module         string
+	Implementation string
+	Mutants        []string
+}
+
+// MutationJudge runs the submitted test suite against the correct
+// implementation of the task and against every mutant of it. There is a test
+// result for every mutant, successful when the suite kills the mutant, so
+// the fraction of mutants killed is the score of the suite. A mutant is
+// killed when a test fails or the time limit is exceeded. A suite failing
+// on the correct implementation kills no mutants. Errors of running the
+// suite other than those caused by the suite itself are judge errors.
+type MutationJudge struct {
+}
+
+func (judge MutationJudge) Run(taskConfig TaskConfig, appConfig ApplicationConfig, processor LanguageProcessor, path string) ([]TestResult, error) {
+	mutation := taskConfig.Mutation
+	if mutation == nil {
+		return nil, errors.New("The task has no mutants")
+	}
+	limits := taskConfig.Task.Limits(processor)
+	run := func(program string) ([]TestResult, error) {
+		return RunSuite(appConfig, mutation.Runner, limits, "", taskConfig.ProgramPath(Program{Path: program}), mutation.Module, path)
+	}
+
+	rejection := ""
+	results, err := run(mutation.Implementation)
+	if _, ok := err.(ReportError); ok || err == ErrTimeLimitExceeded {
+		rejection = "the tests do not run on the correct implementation: " + err.Error()
+	} else if err != nil {
+		return nil, err
+	} else if failed := failedTests(results); len(failed) > 0 {
+		rejection = "the tests fail on the correct implementation: " + strings.Join(failed, ", ")
+	}
+
+	mutants := make([]TestResult, 0, len(mutation.Mutants))
+	for _, mutant := range mutation.Mutants {
+		result := TestResult{Name: strings.TrimSuffix(filepath.Base(mutant), filepath.Ext(mutant))}
+		if rejection != "" {
+			result.Output = rejection
+			mutants = append(mutants, result)
+			continue
+		}
+
+		results, err := run(mutant)
+		if err == ErrTimeLimitExceeded {
+			result.Successful = true
+			result.Output = err.Error()
+		} else if _, ok := err.(ReportError); ok {
+			result.Output = err.Error()
+		} else if err != nil {
+			return nil, err
+		} else if failed := failedTests(results); len(failed) > 0 {
+			result.Successful = true
+			result.Output = "killed by " + strings.Join(failed, ", ")
+		} else {
+			result.Output = "survived"
+		}
+		mutants = append(mutants, result)
+	}
+	return mutants, nil
+}
+
+func failedTests(results []TestResult) []string {
+	failed := make([]string, 0)
+	for _, result := range results {
+		if !result.Successful {
+			failed = append(failed, result.Name)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// OutputJudge grades output-only tasks, whose submissions are the outputs of
+// the tests rather than programs. The path is a directory, an archive or a
+// single file, and every file named as the id of a test, with or without an
+// extension such as 1.out, is compared with the answer of that test.
+type OutputJudge struct {
+}
+
+func (judge OutputJudge) Run(taskConfig TaskConfig, appConfig ApplicationConfig, processor LanguageProcessor, path string) ([]TestResult, error) {
+	files, err := readOutputFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	outputs := make(map[string]string)
+	for _, file := range files {
+		id := OutputTestId(file.Name)
+		if _, exists := outputs[id]; !exists {
+			outputs[id] = string(file.Data)
+		}
+	}
+
+	tests, err := taskConfig.MaterializeTests(appConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]TestResult, 0, len(tests))
+	for _, test := range tests {
+		id := fmt.Sprint(test.Id)
+		out, exists := outputs[id]
+		if !exists {
+			results = append(results, TestResult{Name: id, Output: "missing"})
+			continue
+		}
+		results = append(results, TestResult{
+			Name:       id,
+			Successful: test.Comparator.Compare(out, test.Output),
+			Output:     TrimOutput(out),
+		})
+	}
+	return results, nil
+}
+
+// TestId returns the id of the i-th test of a task, its position counting
+// from one for the tests without an id.
+func TestId(test Test, i int) uint64 {
+	if test.Id != 0 {
+		return test.Id
+	}
+	return uint64(i + 1)
+}
+
+// OutputTestId returns the id of the test an output file is named after, its
+// name up to the first dot.
+func OutputTestId(name string) string {
+	base := path.Base(filepath.ToSlash(name))
+	if i := strings.Index(base, "."); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+func readOutputFiles(root string) ([]SourceFile, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		data, err := ioutil.ReadFile(root)
+		if err != nil {
+			return nil, err
+		}
+		if IsArchive(root) {
+			return ExtractArchive(root, data)
+		}
+		return []SourceFile{{filepath.Base(root), data}}, nil
+	}
+
+	files := make([]SourceFile, 0)
+	err = filepath.Walk(root, func(name string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		files = append(files, SourceFile{filepath.ToSlash(relative), data})
+		return nil
+	})
+	return files, err
+}
@@ -20,22 +20,124 @@
 package coderator
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"strings"
+	"syscall"
+	"time"
 )
 
+var ErrTimeLimitExceeded = errors.New("Time limit exceeded")
+
+// LanguageProcessor runs programs in a language. The time limits of tasks
+// are multiplied by TimeMultiplier for the processor and ExtraMemory
+// megabytes are added to their memory limits, since interpreted languages
+// need both more time and memory than compiled ones. Extensions and
+// Keywords are used to detect the language of submissions, and Entry is the
+// file run for multi-file submissions unless the task names another one.
+// HarnessTemplate is the text/template wrapping solutions of function tasks,
+// and StarterTemplate generates starter code for them.
 type LanguageProcessor struct {
-	Name    string
-	Version string
-	Path    string
-	Exec    string
+	Name            string
+	Version         string
+	Path            string  `json:"-"`
+	Exec            string  `json:"-"`
+	TimeMultiplier  float64 `yaml:"time_multiplier"`
+	ExtraMemory     uint64  `yaml:"extra_memory"`
+	Extensions      []string
+	Keywords        []string `json:"-"`
+	Entry           string
+	HarnessTemplate string `yaml:"harness" json:"-"`
+	StarterTemplate string `yaml:"starter" json:"-"`
+}
+
+// ResourceLimits limits the running time and the memory in megabytes of a
+// program. Zero means no limit.
+type ResourceLimits struct {
+	Time   time.Duration
+	Memory uint64
+}
+
+// Program is an auxiliary program of a task, such as the reference solution
+// or the input validator.
+type Program struct {
+	Path      string
+	Processor string
+}
+
+func (processor LanguageProcessor) Multiplier() float64 {
+	if processor.TimeMultiplier <= 0 {
+		return 1
+	}
+	return processor.TimeMultiplier
 }
 
 func (processor LanguageProcessor) RunFile(args ...string) (string, error) {
-	out, err := exec.Command(processor.Path, args...).Output()
+	return processor.RunFileWithInput("", args...)
+}
+
+// RunFileWithInput runs the processor passing the input to its standard
+// input.
+func (processor LanguageProcessor) RunFileWithInput(input string, args ...string) (string, error) {
+	return processor.RunFileWithLimits(input, ResourceLimits{}, args...)
+}
+
+// RunFileWithLimits is like RunFileWithInput, but kills the process once
+// the time limit is over and returns ErrTimeLimitExceeded. The memory limit
+// is set on the address space of the process with ulimit.
+func (processor LanguageProcessor) RunFileWithLimits(input string, limits ResourceLimits, args ...string) (string, error) {
+	out, err := processor.RunInDir("", input, limits, args...)
 	if err != nil {
 		fmt.Println(err)
 		return "", err
 	}
-	return string(out), nil
+	return out, nil
+}
+
+// RunInDir is like RunFileWithLimits, but runs the processor in the
+// directory and returns the output even if the process fails, as test
+// runners exit with an error when tests fail.
+func (processor LanguageProcessor) RunInDir(dir string, input string, limits ResourceLimits, args ...string) (string, error) {
+	ctx := context.Background()
+	if limits.Time > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Time)
+		defer cancel()
+	}
+
+	cmd := processor.command(ctx, dir, limits, args...)
+	cmd.Stdin = strings.NewReader(input)
+	out, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		err = ErrTimeLimitExceeded
+	}
+	return string(out), err
+}
+
+// Start starts the processor in the directory with the environment variables
+// added to those of the server and its output written to the writer,
+// leaving the caller to wait for the process or to kill it. The process
+// leads a process group of its own, for the caller to kill the processes
+// it starts as well. Only the memory limit applies.
+func (processor LanguageProcessor) Start(dir string, limits ResourceLimits, env []string, output io.Writer, args ...string) (*exec.Cmd, error) {
+	cmd := processor.command(context.Background(), dir, limits, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = output
+	cmd.Stderr = output
+	return cmd, cmd.Start()
+}
+
+func (processor LanguageProcessor) command(ctx context.Context, dir string, limits ResourceLimits, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, processor.Path, args...)
+	if limits.Memory > 0 {
+		script := fmt.Sprintf("ulimit -v %d && exec \"$0\" \"$@\"", limits.Memory*1024)
+		cmd = exec.CommandContext(ctx, "sh", append([]string{"-c", script, processor.Path}, args...)...)
+	}
+	cmd.Dir = dir
+	return cmd
 }
@@ -20,15 +20,15 @@
 package coderator
 
 import (
+	"errors"
 	"fmt"
-	"io"
-	"mime/multipart"
 	"os"
-	"path/filepath"
+	"sync"
+	"time"
 )
 
 var queue = make(map[uint64]bool)
-var verificationResults = make(map[uint64][]bool)
+var queueMutex sync.RWMutex
 
 type VerificationResult int
 
@@ -39,93 +39,257 @@ const (
 	InternalError VerificationResult = 500
 )
 
-func Queue(task Task) {
-	queue[task.Id] = true
+func (result VerificationResult) String() string {
+	switch result {
+	case 0:
+		return "Pending"
+	case Success:
+		return "Success"
+	case TestFailed:
+		return "Test failed"
+	case BadSource:
+		return "Bad source"
+	case InternalError:
+		return "Internal error"
+	}
+	return fmt.Sprint(int(result))
 }
 
-func Dequeue(task Task) {
-	delete(queue, task.Id)
+func Queue(submission Submission) {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+	queue[submission.Id] = true
 }
 
-func IsVerificationQueued(task Task) bool {
-	_, exists := queue[task.Id]
-	return exists
+// QueueIfIdle queues the submission unless it is queued already, reporting
+// whether it was queued, in one step so that concurrent callers cannot both
+// queue it.
+func QueueIfIdle(submission Submission) bool {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+	if queue[submission.Id] {
+		return false
+	}
+	queue[submission.Id] = true
+	return true
 }
 
-func HasVerificationCompleted(task Task) bool {
-	return GetVerificationResults(task) != nil
+func Dequeue(submission Submission) {
+	queueMutex.Lock()
+	defer queueMutex.Unlock()
+	delete(queue, submission.Id)
 }
 
-func GetVerificationResults(task Task) []bool {
-	return verificationResults[task.Id]
+func IsVerificationQueued(submission Submission) bool {
+	queueMutex.RLock()
+	defer queueMutex.RUnlock()
+	_, exists := queue[submission.Id]
+	return exists
 }
 
-func GetTempFileName(task Task) string {
-	return "tmp" + fmt.Sprint(task.Id)
+func HasVerificationCompleted(submission Submission) bool {
+	return submission.Result != 0
 }
 
-func SaveSolution(task Task, file multipart.File) (string, error) {
-	tmpFileName := GetTempFileName(task)
-	tmpFile, err := os.OpenFile(tmpFileName, os.O_WRONLY|os.O_CREATE, 0777)
+func GetVerificationResults(submission Submission) []bool {
+	return submission.Results
+}
+
+// SaveSolution writes the files of the submission into a new working
+// directory of its own and returns the directory and the path of the entry
+// point.
+func SaveSolution(submission Submission) (string, string, error) {
+	dir, err := NewWorkDir(workDirRoot(), fmt.Sprintf("submission%d-", submission.Id))
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	defer tmpFile.Close()
 
-	io.Copy(tmpFile, file)
-	dir, err := filepath.Abs(filepath.Dir(tmpFile.Name()))
+	entry, err := WriteSourceFiles(dir, submission.Files, submission.Entry)
 	if err != nil {
-		fmt.Println(err)
-		return "", err
+		os.RemoveAll(dir)
+		return "", "", err
 	}
-	path := dir + string(filepath.Separator) + tmpFile.Name()
-	return path, nil
+	return dir, entry, nil
 }
 
-func RemoveTempFiles(task Task) {
-	tmpFileName := GetTempFileName(task)
-	err := os.Remove(tmpFileName)
+// VerifyTaskSolution saves the submission, runs the tests and stores the
+// result. The working directory of the submission is removed afterwards,
+// and a panic during verification results in an internal error.
+func VerifyTaskSolution(task Task, submission Submission) (result VerificationResult) {
+	Queue(submission)
+	defer Dequeue(submission)
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println(r)
+			result = InternalError
+			submission.Result = result
+			submission.Results = nil
+			submission.Judged = time.Now()
+			UpdateSubmission(submission)
+		}
+	}()
+
+	dir, path, err := SaveSolution(submission)
 	if err != nil {
 		fmt.Println(err)
+		submission.Result = InternalError
+		submission.Judged = time.Now()
+		UpdateSubmission(submission)
+		return InternalError
 	}
+	defer os.RemoveAll(dir)
+
+	result, results := verify(task, submission.Processor, path)
+	submission.Result = result
+	submission.Results = results
+	submission.Judged = time.Now()
+	UpdateSubmission(submission)
+	return result
 }
 
-func VerifyTaskSolution(task Task, filepath string) VerificationResult {
-	defer RemoveTempFiles(task)
-	Queue(task)
-	defer Dequeue(task)
+// RejudgeAll queues the submissions and judges their stored sources again one
+// by one in the background. The previous verdicts are kept in the history of
+// the submissions, and submissions still being verified are skipped.
+func RejudgeAll(submissions []Submission) []uint64 {
+	queued := make([]Submission, 0, len(submissions))
+	ids := make([]uint64, 0, len(submissions))
+	for _, submission := range submissions {
+		if !QueueIfIdle(submission) {
+			continue
+		}
+		submission = archiveVerdict(submission)
+		queued = append(queued, submission)
+		ids = append(ids, submission.Id)
+	}
+
+	go func() {
+		for _, submission := range queued {
+			task, err := database.FindTaskById(submission.TaskId)
+			if err != nil || task == nil {
+				fmt.Println(err)
+				submission.Result = InternalError
+				submission.Judged = time.Now()
+				UpdateSubmission(submission)
+				Dequeue(submission)
+				continue
+			}
+			VerifyTaskSolution(*task, submission)
+		}
+	}()
+	return ids
+}
 
-	tests, err := database.FindTestsByTaskId(task.Id)
-	if err != nil {
-		fmt.Println(err)
-		verificationResults[task.Id] = nil
-		return InternalError
+func archiveVerdict(submission Submission) Submission {
+	if HasVerificationCompleted(submission) {
+		submission.History = append(submission.History, Verdict{
+			Result:  submission.Result,
+			Results: submission.Results,
+			Judged:  submission.Judged,
+		})
 	}
+	submission.Result = 0
+	submission.Results = nil
+	UpdateSubmission(submission)
+	return submission
+}
 
+func verify(task Task, processorName string, filepath string) (VerificationResult, []bool) {
 	config := Config{}
 	appConfig, err := config.ApplicationConfig()
 	if err != nil {
-		return InternalError
+		return InternalError, nil
+	}
+
+	if task.Type == TaskTypeOutput {
+		return verifyWithJudge(task, *appConfig, LanguageProcessor{}, filepath)
 	}
 
-	processor := appConfig.FindProcessorByName(task.Processor)
+	processor := appConfig.FindProcessorByName(processorName)
 	if processor == nil {
-		return InternalError
+		return InternalError, nil
 	}
 
 	sourceValidator := FindSourceValidatorByProcessor(*processor)
-	if !sourceValidator.Valid() {
-		return BadSource
+	if sourceValidator != nil && !sourceValidator.Valid() {
+		return BadSource, nil
+	}
+
+	if task.Type != "" && task.Type != TaskTypeIO {
+		return verifyWithJudge(task, *appConfig, *processor, filepath)
+	}
+
+	tests, err := testsForJudging(task.Id, *appConfig)
+	if err != nil {
+		fmt.Println(err)
+		return InternalError, nil
+	}
+
+	program, cleanup, err := task.PrepareSolution(*processor, filepath)
+	if err != nil {
+		fmt.Println(err)
+		return InternalError, nil
 	}
+	defer cleanup()
 
-	tester := Tester{}
-	results := tester.RunTests(*processor, filepath, tests)
-	verificationResults[task.Id] = results
+	tester := task.Tester(*processor)
+	results := tester.RunTests(*processor, program, tests)
 
 	for _, result := range results {
 		if !result {
-			return TestFailed
+			return TestFailed, results
+		}
+	}
+	return Success, results
+}
+
+// testsForJudging materializes the tests of task files, generating again
+// the tests whose generator or reference solution changed. Other
+// repositories keep the tests themselves.
+func testsForJudging(taskId uint64, appConfig ApplicationConfig) ([]Test, error) {
+	config, ok := database.(Config)
+	if !ok {
+		return database.FindTestsByTaskId(taskId)
+	}
+	taskConfig, err := config.FindTaskConfigById(taskId)
+	if err != nil {
+		return nil, err
+	}
+	return taskConfig.MaterializeTests(appConfig)
+}
+
+// verifyWithJudge verifies solutions of the task types judged from the task
+// file rather than from the tests in the repository.
+func verifyWithJudge(task Task, appConfig ApplicationConfig, processor LanguageProcessor, filepath string) (VerificationResult, []bool) {
+	judge := FindJudgeByTask(task)
+	if judge == nil {
+		fmt.Println(errors.New("Unknown task type " + task.Type))
+		return InternalError, nil
+	}
+
+	config := Config{}
+	taskConfig, err := config.FindTaskConfigById(task.Id)
+	if err != nil {
+		fmt.Println(err)
+		return InternalError, nil
+	}
+
+	testResults, err := judge.Run(*taskConfig, appConfig, processor, filepath)
+	if _, ok := err.(ReportError); ok || err == ErrTimeLimitExceeded {
+		return TestFailed, []bool{false}
+	}
+	if err != nil {
+		fmt.Println(err)
+		return InternalError, nil
+	}
+
+	result := Success
+	results := make([]bool, 0, len(testResults))
+	for _, testResult := range testResults {
+		results = append(results, testResult.Successful)
+		if !testResult.Successful {
+			result = TestFailed
 		}
 	}
-	return Success
+	return result, results
 }
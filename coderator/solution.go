@@ -0,0 +1,169 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	VerdictAccepted     = "accepted"
+	VerdictRejected     = "rejected"
+	VerdictWrongAnswer  = "wrong-answer"
+	VerdictRuntimeError = "runtime-error"
+	VerdictTimeLimit    = "time-limit-exceeded"
+)
+
+// Solution is a solution of the task known to be either correct or wrong.
+// The verdict it is expected to get defaults to accepted.
+type Solution struct {
+	Path      string
+	Processor string
+	Verdict   string
+}
+
+func (solution Solution) Program() Program {
+	return Program{Path: solution.Path, Processor: solution.Processor}
+}
+
+func (solution Solution) ExpectedVerdict() string {
+	if solution.Verdict == "" {
+		return VerdictAccepted
+	}
+	return solution.Verdict
+}
+
+// Matches reports whether the actual verdict satisfies the expected one.
+// Any verdict but accepted satisfies the rejected expectation.
+func (solution Solution) Matches(verdict string) bool {
+	expected := solution.ExpectedVerdict()
+	if expected == VerdictRejected {
+		return verdict != VerdictAccepted
+	}
+	return expected == verdict
+}
+
+// SolutionVerdict reduces the results of the tests to a single verdict, the
+// one of the first failed test.
+func SolutionVerdict(results []TestResult) string {
+	for _, result := range results {
+		if result.Error == ErrTimeLimitExceeded {
+			return VerdictTimeLimit
+		}
+		if result.Error != nil {
+			return VerdictRuntimeError
+		}
+		if !result.Successful {
+			return VerdictWrongAnswer
+		}
+	}
+	return VerdictAccepted
+}
+
+// ReferenceSolution returns the first solution expected to be accepted.
+func (taskConfig TaskConfig) ReferenceSolution() *Program {
+	for _, solution := range taskConfig.Solutions {
+		if solution.ExpectedVerdict() == VerdictAccepted {
+			program := solution.Program()
+			return &program
+		}
+	}
+	return nil
+}
+
+// CheckSolutions runs the solutions of the task on its tests and reports
+// the solutions whose verdicts differ from the expected ones.
+func CheckSolutions(taskConfig TaskConfig, appConfig ApplicationConfig) []Problem {
+	problems := make([]Problem, 0)
+
+	judge := FindJudgeByTask(taskConfig.Task)
+	if judge == nil {
+		return append(problems, Problem{taskConfig.path, "unknown task type " + taskConfig.Task.Type})
+	}
+
+	for _, solution := range taskConfig.Solutions {
+		report := func(format string, args ...interface{}) {
+			message := solution.Path + ": " + fmt.Sprintf(format, args...)
+			problems = append(problems, Problem{taskConfig.path, message})
+		}
+
+		processor := &LanguageProcessor{}
+		if taskConfig.Task.Type != TaskTypeOutput {
+			processor = appConfig.FindProcessorByName(solution.Processor)
+		}
+		if processor == nil {
+			report("unknown processor %s", solution.Processor)
+			continue
+		}
+
+		results, err := judge.Run(taskConfig, appConfig, *processor, taskConfig.ProgramPath(solution.Program()))
+		if err == ErrTimeLimitExceeded {
+			results = []TestResult{{Error: err}}
+		} else if err != nil {
+			report("%s", err)
+			continue
+		}
+
+		failed := make([]string, 0)
+		for i, result := range results {
+			if result.Successful {
+				continue
+			}
+			if result.Name != "" {
+				failed = append(failed, result.Name)
+			} else {
+				failed = append(failed, fmt.Sprint(i+1))
+			}
+		}
+
+		verdict := SolutionVerdict(results)
+		if solution.Matches(verdict) {
+			continue
+		}
+		if len(failed) > 0 {
+			report("expected %s, got %s on tests %s", solution.ExpectedVerdict(), verdict, strings.Join(failed, ", "))
+		} else {
+			report("expected %s, got %s", solution.ExpectedVerdict(), verdict)
+		}
+	}
+	return problems
+}
+
+// CheckAllSolutions runs CheckSolutions for every task file in the directory.
+func CheckAllSolutions(dir string, appConfig ApplicationConfig) []Problem {
+	problems := make([]Problem, 0)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+Extension))
+	if err != nil {
+		return append(problems, Problem{dir, err.Error()})
+	}
+
+	for _, path := range paths {
+		taskConfig, err := LoadTaskConfig(path)
+		if err != nil {
+			problems = append(problems, Problem{path, err.Error()})
+			continue
+		}
+		problems = append(problems, CheckSolutions(*taskConfig, appConfig)...)
+	}
+	return problems
+}
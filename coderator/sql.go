@@ -0,0 +1,184 @@
+//go:build cgo
+// +build cgo
+
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultQueryTimeout limits the queries of the tasks without a time limit.
+const DefaultQueryTimeout = 10 * time.Second
+
+// sqlDriver opens in-memory SQLite databases that cannot attach database
+// files, so that queries cannot reach the file system.
+const sqlDriver = "sqlite3_coderator"
+
+func init() {
+	sql.Register(sqlDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			conn.SetLimit(sqlite3.SQLITE_LIMIT_ATTACHED, 0)
+			return nil
+		},
+	})
+}
+
+// RunQuery creates a fresh in-memory database from the schema and the seed
+// data and runs the query on it. The rows of the result are returned one
+// per line, with the columns separated by "|" and NULL values written as
+// NULL.
+func RunQuery(schema string, seed string, query string, timeout time.Duration) (string, error) {
+	db, err := sql.Open(sqlDriver, ":memory:")
+	if err != nil {
+		return "", err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	for _, script := range []string{schema, seed} {
+		if strings.TrimSpace(script) == "" {
+			continue
+		}
+		if _, err = db.Exec(script); err != nil {
+			return "", fmt.Errorf("Could not prepare the database: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return "", queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, 0)
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if err = rows.Scan(pointers...); err != nil {
+			return "", err
+		}
+		fields := make([]string, len(values))
+		for i, value := range values {
+			fields[i] = formatValue(value)
+		}
+		lines = append(lines, strings.Join(fields, "|"))
+	}
+	if err = rows.Err(); err != nil {
+		return "", queryError(ctx, err)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func queryError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeLimitExceeded
+	}
+	return err
+}
+
+func formatValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(value)
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	case time.Time:
+		return value.Format(time.RFC3339)
+	}
+	return fmt.Sprint(value)
+}
+
+// SQLJudge runs the query of the solution in a fresh database for every
+// test, created from the schema and the seed data of the test. Tests without
+// an answer are answered by the query of the reference solution, and the
+// result sets are compared regardless of the order of their rows unless the
+// test names another comparator.
+type SQLJudge struct {
+}
+
+func (judge SQLJudge) Run(taskConfig TaskConfig, appConfig ApplicationConfig, processor LanguageProcessor, path string) ([]TestResult, error) {
+	query, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tests, err := taskConfig.MaterializeTests(appConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := taskConfig.Task.Limits(processor).Time
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+
+	var reference []byte
+	results := make([]TestResult, 0, len(tests))
+	for i, test := range tests {
+		expected := test.Output
+		if !test.HasAnswer() {
+			if reference == nil {
+				program := taskConfig.ReferenceSolution()
+				if program == nil {
+					return nil, errors.New("No answer and no reference solution")
+				}
+				if reference, err = ioutil.ReadFile(taskConfig.ProgramPath(*program)); err != nil {
+					return nil, err
+				}
+			}
+			if expected, err = RunQuery(test.Schema, test.Seed, string(reference), timeout); err != nil {
+				return nil, fmt.Errorf("test %d: reference solution: %v", i+1, err)
+			}
+		}
+
+		comparator := test.Comparator
+		if comparator.Name == "" {
+			comparator.Name = "unordered"
+		}
+
+		output, err := RunQuery(test.Schema, test.Seed, string(query), timeout)
+		if err != nil {
+			results = append(results, TestResult{Error: err})
+			continue
+		}
+		results = append(results, TestResult{Output: output, Successful: comparator.Compare(output, expected)})
+	}
+	return results, nil
+}
@@ -0,0 +1,83 @@
+//go:build cgo
+// +build cgo
+
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRunQuery(t *testing.T) {
+	schema := "CREATE TABLE t (a INTEGER, b TEXT, c REAL);"
+	seed := "INSERT INTO t VALUES (1, 'x', 0.5), (2, NULL, 2);"
+	out, err := RunQuery(schema, seed, "SELECT a, b, c FROM t ORDER BY a DESC", time.Second)
+	if err != nil || out != "2|NULL|2\n1|x|0.5" {
+		t.Errorf("%q %v", out, err)
+	}
+
+	if _, err := RunQuery(schema, seed, "ATTACH DATABASE 'other.db' AS other", time.Second); err == nil {
+		t.Error("database attached")
+	}
+	if !(UnorderedComparator{}).Compare(out, "1|x|0.5\n2|NULL|2\n") || (OrderedComparator{}).Compare(out, "1|x|0.5\n2|NULL|2") {
+		t.Error("unexpected comparison")
+	}
+}
+
+func TestSQLJudge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "reference.sql"), []byte("SELECT a FROM t WHERE a > 1"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "query.sql"), []byte("SELECT a FROM t WHERE a > 5"), 0644)
+
+	task := `task:
+  type: sql
+tests:
+  - schema: CREATE TABLE t (a INTEGER);
+    seed: INSERT INTO t VALUES (1), (2);
+    output: ""
+  - schema: CREATE TABLE t (a INTEGER);
+    seed: INSERT INTO t VALUES (1), (2);
+solutions:
+  - path: reference.sql
+`
+	taskConfig := TaskConfig{dir: dir}
+	if err = yaml.UnmarshalStrict([]byte(task), &taskConfig); err != nil {
+		t.Fatal(err)
+	}
+	if !taskConfig.Tests[0].HasAnswer() || taskConfig.Tests[1].HasAnswer() {
+		t.Errorf("%+v", taskConfig.Tests)
+	}
+
+	results, err := SQLJudge{}.Run(taskConfig, ApplicationConfig{}, LanguageProcessor{}, filepath.Join(dir, "query.sql"))
+	if err != nil || len(results) != 2 || !results[0].Successful || results[1].Successful {
+		t.Errorf("%+v %v", results, err)
+	}
+}
@@ -0,0 +1,210 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const (
+	DefaultStressIterations = 100
+	MaxStressIterations     = 1000
+)
+
+// MaxShrinkAttempts bounds the runs spent on minimizing a counterexample.
+const MaxShrinkAttempts = 200
+
+type StressOptions struct {
+	Generator  Generator
+	Iterations int
+	Seed       int64
+}
+
+type Counterexample struct {
+	Seed     int64
+	Input    string
+	Expected string
+	Output   string
+	Error    string
+}
+
+type StressReport struct {
+	Iterations     int
+	Counterexample *Counterexample
+}
+
+// Stress runs the generator with consecutive seeds starting from the given
+// one and compares the outputs of the candidate and the reference solution
+// on every generated input. The first failing input is shrunk line by line
+// while it keeps failing and, to stay within the constraints, while the
+// input validator of the task accepts it. Without a validator the shrunk
+// inputs the reference solution fails on are discarded instead. The
+// candidate runs within the limits of the task, and exceeding them makes a
+// counterexample as well.
+func Stress(taskConfig TaskConfig, appConfig ApplicationConfig, processor LanguageProcessor, candidate string, options StressOptions) (*StressReport, error) {
+	reference := taskConfig.ReferenceSolution()
+	if reference == nil {
+		return nil, errors.New("The task has no reference solution")
+	}
+
+	program, cleanup, err := taskConfig.Task.PrepareSolution(processor, candidate)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	comparator := taskConfig.DefaultComparator()
+	check := func(input string) (*Counterexample, error) {
+		expected, err := taskConfig.RunSolution(appConfig, *reference, input)
+		if err != nil {
+			return nil, err
+		}
+		expected = TrimOutput(expected)
+
+		output, err := taskConfig.Task.Tester(processor).Execute(processor, program, input)
+		if err != nil {
+			return &Counterexample{Input: input, Expected: expected, Error: err.Error()}, nil
+		}
+		output = TrimOutput(output)
+		if comparator.Compare(output, expected) {
+			return nil, nil
+		}
+		return &Counterexample{Input: input, Expected: expected, Output: output}, nil
+	}
+
+	generator := options.Generator
+	report := &StressReport{}
+	for i := 0; i < options.Iterations; i++ {
+		generator.Seed = options.Seed + int64(i)
+		input, err := taskConfig.RunProgram(appConfig, generator.Program(), "", generator.Arguments()...)
+		if err != nil {
+			return nil, err
+		}
+		report.Iterations++
+
+		counterexample, err := check(input)
+		if err != nil {
+			return nil, err
+		}
+		if counterexample == nil {
+			continue
+		}
+
+		counterexample = shrink(counterexample, func(input string) (*Counterexample, error) {
+			if taskConfig.Validator != nil {
+				violation, err := taskConfig.ValidateInput(appConfig, input)
+				if err != nil || violation != "" {
+					return nil, err
+				}
+			}
+			return check(input)
+		})
+		counterexample.Seed = generator.Seed
+		report.Counterexample = counterexample
+		return report, nil
+	}
+	return report, nil
+}
+
+// shrink removes chunks of lines of decreasing size from the input as long
+// as the check still finds the smaller input failing.
+func shrink(counterexample *Counterexample, check func(input string) (*Counterexample, error)) *Counterexample {
+	lines := strings.SplitAfter(counterexample.Input, "\n")
+	attempts := 0
+
+	for chunk := len(lines) / 2; chunk > 0; chunk /= 2 {
+		for i := 0; i+chunk <= len(lines) && attempts < MaxShrinkAttempts; {
+			attempts++
+			smaller := append(append([]string{}, lines[:i]...), lines[i+chunk:]...)
+			found, err := check(strings.Join(smaller, ""))
+			if err == nil && found != nil {
+				lines = smaller
+				counterexample = found
+			} else {
+				i += chunk
+			}
+		}
+	}
+	return counterexample
+}
+
+// StressGenerator returns the generator of the first generated test of the
+// task, used for stress testing unless another one is given.
+func (taskConfig TaskConfig) StressGenerator() *Generator {
+	for _, test := range taskConfig.Tests {
+		if test.Generator != nil {
+			generator := *test.Generator
+			return &generator
+		}
+	}
+	return nil
+}
+
+// StressTask stress tests the source against the task with the given id
+// using the generator of its first generated test. The source is run with
+// the named processor, the task processor if the name is empty.
+func StressTask(taskId uint64, source []byte, processorName string, iterations int, seed int64) (*StressReport, error) {
+	config := Config{}
+	appConfig, err := config.ApplicationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	taskConfig, err := config.FindTaskConfigById(taskId)
+	if err != nil {
+		return nil, err
+	}
+
+	if processorName == "" {
+		processorName = taskConfig.Task.Processor
+	}
+	processor := appConfig.FindProcessorByName(processorName)
+	if processor == nil || !taskConfig.Task.AllowsProcessor(processorName) {
+		return nil, errors.New("The task cannot be solved with " + processorName)
+	}
+
+	generator := taskConfig.StressGenerator()
+	if generator == nil {
+		return nil, errors.New("The task has no generator")
+	}
+
+	dir, err := NewWorkDir(workDirRoot(), "stress")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	file, err := ioutil.TempFile(dir, "source")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = file.Write(source)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	options := StressOptions{Generator: *generator, Iterations: iterations, Seed: seed}
+	return Stress(*taskConfig, *appConfig, *processor, file.Name(), options)
+}
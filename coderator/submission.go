@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"sync"
+	"time"
+)
+
+// Verdict is a previous result of a submission replaced by rejudging.
+type Verdict struct {
+	Result  VerificationResult
+	Results []bool
+	Judged  time.Time
+}
+
+type Submission struct {
+	Id        uint64
+	TaskId    uint64
+	User      string
+	Processor string
+	Detected  bool
+	Time      time.Time
+	Result    VerificationResult
+	Results   []bool
+	Judged    time.Time
+	History   []Verdict
+	Entry     string
+	Files     []SourceFile `json:"-"`
+}
+
+var submissions = make(map[uint64]Submission)
+var submissionsMutex sync.RWMutex
+var lastSubmissionId uint64
+
+func NewSubmission(task Task, user string, processor string, files []SourceFile, entry string) Submission {
+	submissionsMutex.Lock()
+	defer submissionsMutex.Unlock()
+
+	lastSubmissionId++
+	submission := Submission{
+		Id:        lastSubmissionId,
+		TaskId:    task.Id,
+		User:      user,
+		Processor: processor,
+		Time:      time.Now(),
+		Entry:     entry,
+		Files:     files,
+	}
+	submissions[submission.Id] = submission
+	return submission
+}
+
+func UpdateSubmission(submission Submission) {
+	submissionsMutex.Lock()
+	defer submissionsMutex.Unlock()
+	submissions[submission.Id] = submission
+}
+
+func FindSubmissionById(id uint64) *Submission {
+	submissionsMutex.RLock()
+	defer submissionsMutex.RUnlock()
+
+	submission, exists := submissions[id]
+	if !exists {
+		return nil
+	}
+	return &submission
+}
+
+// FindSubmissionsByTaskAndUser returns the submissions of the user for the
+// task ordered by submission time.
+func FindSubmissionsByTaskAndUser(taskId uint64, user string) []Submission {
+	submissionsMutex.RLock()
+	defer submissionsMutex.RUnlock()
+
+	found := make([]Submission, 0)
+	for id := uint64(1); id <= lastSubmissionId; id++ {
+		submission, exists := submissions[id]
+		if exists && submission.TaskId == taskId && submission.User == user {
+			found = append(found, submission)
+		}
+	}
+	return found
+}
+
+func AllSubmissions() []Submission {
+	submissionsMutex.RLock()
+	defer submissionsMutex.RUnlock()
+
+	all := make([]Submission, 0, len(submissions))
+	for id := uint64(1); id <= lastSubmissionId; id++ {
+		if submission, exists := submissions[id]; exists {
+			all = append(all, submission)
+		}
+	}
+	return all
+}
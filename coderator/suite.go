@@ -0,0 +1,293 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatJUnit = "junit"
+	FormatTAP   = "tap"
+)
+
+// TestSuite is the test suite of a test-suite task, run against the
+// solution by the runner of the given name.
+type TestSuite struct {
+	Path   string
+	Runner string
+}
+
+// RunnerConfig runs test suites with the processor, passing it the program
+// at Path, if any, and the arguments. The arguments may refer to the suite
+// file as {suite} and to the JUnit report as {report}; the report is read
+// from the standard output unless the arguments refer to it.
+type RunnerConfig struct {
+	Name      string
+	Processor string
+	Path      string
+	Args      []string
+	Format    string
+}
+
+func (config ApplicationConfig) FindRunnerByName(name string) *RunnerConfig {
+	for _, runner := range config.Runners {
+		if runner.Name == name {
+			return &runner
+		}
+	}
+	return nil
+}
+
+// SuiteJudge copies the directory of the solution, with the solution named
+// as the entry point of the task, and the test suite into a new working
+// directory and runs the suite there, so that the suite can import the
+// solution and the solution its other files. Each test case of the report
+// is a test result.
+type SuiteJudge struct {
+}
+
+func (judge SuiteJudge) Run(taskConfig TaskConfig, appConfig ApplicationConfig, processor LanguageProcessor, path string) ([]TestResult, error) {
+	suite := taskConfig.Suite
+	if suite == nil {
+		return nil, errors.New("The task has no test suite")
+	}
+
+	module := taskConfig.Task.EntryPoint(processor)
+	if module == "" {
+		module = filepath.Base(path)
+	}
+	suitePath := taskConfig.ProgramPath(Program{Path: suite.Path})
+	return RunSuite(appConfig, suite.Runner, taskConfig.Task.Limits(processor), filepath.Dir(path), path, module, suitePath)
+}
+
+// ReportError is returned by RunSuite when the suite runs but leaves no
+// readable report, as opposed to the errors of running it at all.
+type ReportError struct {
+	Err error
+}
+
+func (e ReportError) Error() string {
+	return e.Err.Error()
+}
+
+// RunSuite copies the files of the source directory, if any, the program,
+// named as the module, and the test suite into a new working directory and
+// runs the suite there with the runner of the given name. A suite reporting
+// no tests is rejected, as it proves nothing.
+func RunSuite(appConfig ApplicationConfig, runnerName string, limits ResourceLimits, sources string, program string, module string, suite string) ([]TestResult, error) {
+	runner := appConfig.FindRunnerByName(runnerName)
+	if runner == nil {
+		return nil, errors.New("Unknown runner " + runnerName)
+	}
+	runnerProcessor := appConfig.FindProcessorByName(runner.Processor)
+	if runnerProcessor == nil {
+		return nil, errors.New("Unknown processor " + runner.Processor)
+	}
+
+	if filepath.Base(suite) == module {
+		return nil, errors.New("The test suite is named as the module " + module)
+	}
+
+	dir, err := NewWorkDir(workDirRoot(), "suite")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if sources != "" {
+		if err = copyTree(sources, dir); err != nil {
+			return nil, err
+		}
+	}
+	if err = copyFile(program, filepath.Join(dir, module)); err != nil {
+		return nil, err
+	}
+	suitePath := filepath.Join(dir, filepath.Base(suite))
+	if err = copyFile(suite, suitePath); err != nil {
+		return nil, err
+	}
+
+	reportPath := filepath.Join(dir, "report.xml")
+	usesReport := false
+	args := make([]string, 0, len(runner.Args)+1)
+	if runner.Path != "" {
+		path, err := filepath.Abs(runner.Path)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, path)
+	}
+	for _, arg := range runner.Args {
+		if strings.Contains(arg, "{report}") {
+			usesReport = true
+		}
+		arg = strings.Replace(arg, "{suite}", suitePath, -1)
+		arg = strings.Replace(arg, "{report}", reportPath, -1)
+		args = append(args, arg)
+	}
+
+	out, err := runnerProcessor.RunInDir(dir, "", limits, args...)
+	if err == ErrTimeLimitExceeded {
+		return nil, err
+	}
+	if usesReport {
+		data, readErr := ioutil.ReadFile(reportPath)
+		if readErr != nil {
+			return nil, ReportError{fmt.Errorf("No report: %v", readErr)}
+		}
+		out = string(data)
+	}
+
+	var results []TestResult
+	switch runner.Format {
+	case FormatJUnit:
+		results, err = ParseJUnit([]byte(out))
+	case FormatTAP:
+		results, err = ParseTAP(out)
+	default:
+		return nil, errors.New("Unknown report format " + runner.Format)
+	}
+	if err != nil {
+		return nil, ReportError{err}
+	}
+	if len(results) == 0 {
+		return nil, ReportError{errors.New("no tests")}
+	}
+	return results, nil
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure"`
+	Error     *junitFailure `xml:"error"`
+	Skipped   *struct{}     `xml:"skipped"`
+}
+
+type junitSuite struct {
+	Cases  []junitCase  `xml:"testcase"`
+	Suites []junitSuite `xml:"testsuite"`
+}
+
+// ParseJUnit reads the test cases of a JUnit XML report, either a single
+// test suite or a list of them. Skipped test cases are left out.
+func ParseJUnit(data []byte) ([]TestResult, error) {
+	root := junitSuite{}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+
+	results := make([]TestResult, 0)
+	var collect func(suite junitSuite)
+	collect = func(suite junitSuite) {
+		for _, testCase := range suite.Cases {
+			if testCase.Skipped != nil {
+				continue
+			}
+			name := testCase.Name
+			if testCase.ClassName != "" {
+				name = testCase.ClassName + "." + name
+			}
+			result := TestResult{Name: name, Successful: true}
+			for _, failure := range []*junitFailure{testCase.Failure, testCase.Error} {
+				if failure != nil {
+					result.Successful = false
+					result.Output = strings.TrimSpace(failure.Message + "\n" + failure.Text)
+				}
+			}
+			results = append(results, result)
+		}
+		for _, child := range suite.Suites {
+			collect(child)
+		}
+	}
+	collect(root)
+	return results, nil
+}
+
+// ParseTAP reads the test points of a TAP stream. Diagnostics following a
+// test point become its output, skipped points are left out, and points
+// planned but missing are failed.
+func ParseTAP(out string) ([]TestResult, error) {
+	results := make([]TestResult, 0)
+	planned := -1
+	seen := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		switch {
+		case strings.HasPrefix(line, "1.."):
+			if count, err := strconv.Atoi(strings.Fields(line[3:] + " ")[0]); err == nil {
+				planned = count
+			}
+		case strings.HasPrefix(line, "ok"), strings.HasPrefix(line, "not ok"):
+			seen++
+			successful := strings.HasPrefix(line, "ok")
+			description := strings.TrimPrefix(strings.TrimPrefix(line, "not ok"), "ok")
+			description = strings.TrimLeft(description, " 0123456789")
+			description = strings.TrimSpace(strings.TrimPrefix(description, "-"))
+
+			name, directive := description, ""
+			if i := strings.Index(description, "#"); i >= 0 {
+				name = strings.TrimSpace(description[:i])
+				directive = strings.ToUpper(strings.TrimSpace(description[i+1:]))
+			}
+			if strings.HasPrefix(directive, "SKIP") {
+				continue
+			}
+			if strings.HasPrefix(directive, "TODO") {
+				successful = true
+			}
+			results = append(results, TestResult{Name: name, Successful: successful})
+		case strings.HasPrefix(line, "#") && len(results) > 0:
+			last := &results[len(results)-1]
+			if !last.Successful {
+				last.Output = strings.TrimSpace(last.Output + "\n" + strings.TrimSpace(line[1:]))
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if planned < 0 && seen == 0 {
+		return nil, errors.New("No test results in the TAP output")
+	}
+
+	for i := seen; i < planned; i++ {
+		results = append(results, TestResult{Name: fmt.Sprintf("test %d", i+1), Output: "missing"})
+	}
+	return results, nil
+}
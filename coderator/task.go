@@ -19,9 +19,93 @@
 
 package coderator
 
+import "time"
+
+// AnyProcessor among the processors of a task allows solving it with any
+// processor of the application.
+const AnyProcessor = "any"
+
+const (
+	InputArgument = "argument"
+	InputStdin    = "stdin"
+)
+
 type Task struct {
-	Id        uint64
-	Title     string
-	Text      string
-	Processor string
+	Id          uint64
+	Title       string
+	Text        string
+	Type        string `yaml:",omitempty"`
+	Processor   string
+	Processors  []string `yaml:",omitempty"`
+	Input       string   `yaml:",omitempty"`
+	Quota       Quota
+	TimeLimit   time.Duration            `yaml:"time_limit,omitempty"`
+	TimeLimits  map[string]time.Duration `yaml:"time_limits,omitempty"`
+	MemoryLimit uint64                   `yaml:"memory_limit,omitempty"`
+	Entry       string                   `yaml:",omitempty"`
+	Function    *Function                `yaml:",omitempty"`
+	Templates   map[string]string        `yaml:",omitempty" json:"-"`
+}
+
+// ReadsStdin reports whether the solutions of the task read the input of a
+// test from the standard input rather than from their last argument. The
+// harnesses of function tasks always read the standard input.
+func (task Task) ReadsStdin() bool {
+	return task.Input == InputStdin || task.Function != nil
+}
+
+// Tester returns the tester running the solutions of the task written for
+// the processor.
+func (task Task) Tester(processor LanguageProcessor) Tester {
+	return Tester{Limits: task.Limits(processor), Stdin: task.ReadsStdin()}
+}
+
+// Limits returns the limits of the task for the processor. A time limit
+// calibrated for the processor is taken as is, otherwise the base time
+// limit is multiplied by the time multiplier of the processor.
+func (task Task) Limits(processor LanguageProcessor) ResourceLimits {
+	limits := ResourceLimits{}
+	if limit, ok := task.TimeLimits[processor.Name+processor.Version]; ok {
+		limits.Time = limit
+	} else {
+		limits.Time = time.Duration(float64(task.TimeLimit) * processor.Multiplier())
+	}
+	if task.MemoryLimit > 0 {
+		limits.Memory = task.MemoryLimit + processor.ExtraMemory
+	}
+	return limits
+}
+
+// AllowsProcessor reports whether the task can be solved with the processor
+// of the given name, either the task processor or one of the additional
+// processors.
+func (task Task) AllowsProcessor(name string) bool {
+	if name == task.Processor {
+		return true
+	}
+	for _, processor := range task.Processors {
+		if processor == name || processor == AnyProcessor {
+			return true
+		}
+	}
+	return false
+}
+
+func (task Task) AllowedProcessors(appConfig ApplicationConfig) []LanguageProcessor {
+	processors := make([]LanguageProcessor, 0)
+	for _, processor := range appConfig.Processors {
+		if task.AllowsProcessor(processor.Name + processor.Version) {
+			processors = append(processors, processor)
+		}
+	}
+	return processors
+}
+
+// EntryPoint returns the file run for submissions made with the processor,
+// empty if neither the task nor the processor name one.
+func (task Task) EntryPoint(processor LanguageProcessor) string {
+	if task.Entry != "" {
+		return task.Entry
+	}
+	return processor.Entry
 }
@@ -21,30 +21,93 @@ package coderator
 
 import (
 	"fmt"
+	"strings"
 )
 
 type Test struct {
 	Id         uint64
 	Input      string
 	Output     string
-	Comparator Comparator
+	Schema     string        `yaml:",omitempty"`
+	Seed       string        `yaml:",omitempty"`
+	Request    *HTTPRequest  `yaml:",omitempty" json:"-"`
+	Response   *HTTPResponse `yaml:",omitempty" json:"-"`
+	Args       []interface{} `yaml:",omitempty" json:"-"`
+	Result     interface{}   `yaml:",omitempty" json:"-"`
+	Files      TestFiles
+	Generator  *Generator
+	Comparator ComparatorConfig
+	unanswered bool
+}
+
+// UnmarshalYAML notes whether the test gives its answer, since an empty
+// output is an answer of its own.
+func (test *Test) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain Test
+	if err := unmarshal((*plain)(test)); err != nil {
+		return err
+	}
+
+	fields := make(map[string]interface{})
+	if err := unmarshal(&fields); err != nil {
+		return err
+	}
+	_, answered := fields["output"]
+	test.unanswered = !answered && test.Files.Answer == ""
+	return nil
+}
+
+// HasAnswer reports whether the expected output of the test is given, even
+// if it is empty.
+func (test Test) HasAnswer() bool {
+	return !test.unanswered
 }
 
 type TestResult struct {
+	Name       string
 	Successful bool
+	Output     string
+	Error      error
 }
 
+// Tester runs the tests within the limits, passing the input as the last
+// argument of the program or, for Stdin, to its standard input.
 type Tester struct {
-	// TODO
+	Limits ResourceLimits
+	Stdin  bool
 }
 
-func (t Tester) RunTest(processor LanguageProcessor, filepath string, test Test) bool {
-	out, err := processor.RunFile(filepath, test.Input)
+// Execute runs the program on the input.
+func (t Tester) Execute(processor LanguageProcessor, filepath string, input string) (string, error) {
+	if t.Stdin {
+		return processor.RunFileWithLimits(input, t.Limits, filepath)
+	}
+	return processor.RunFileWithLimits("", t.Limits, filepath, input)
+}
+
+// Run runs the test and keeps the output of the program for reporting.
+func (t Tester) Run(processor LanguageProcessor, filepath string, test Test) TestResult {
+	out, err := t.Execute(processor, filepath, test.Input)
 	if err != nil {
-		fmt.Println(err)
-		return false
+		return TestResult{Output: out, Error: err}
+	}
+
+	return TestResult{
+		Successful: test.Comparator.Compare(out, test.Output),
+		Output:     TrimOutput(out),
+	}
+}
+
+func TrimOutput(output string) string {
+	return strings.TrimRight(output, " \t\r\n")
+}
+
+func (t Tester) RunTest(processor LanguageProcessor, filepath string, test Test) bool {
+	result := t.Run(processor, filepath, test)
+	if result.Error != nil {
+		fmt.Println(result.Error)
 	}
-	return test.Comparator.Compare(string(out), test.Output)
+	return result.Successful
 }
 
 func (t Tester) RunTests(processor LanguageProcessor, filepath string, tests []Test) []bool {
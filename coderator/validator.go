@@ -19,6 +19,14 @@
 
 package coderator
 
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
 type SourceValidator interface {
 	CanValidate() bool
 	Valid() bool
@@ -27,3 +35,73 @@ type SourceValidator interface {
 func FindSourceValidatorByProcessor(processor LanguageProcessor) SourceValidator {
 	return nil
 }
+
+// ValidateInput runs the input validator of the task on the input and
+// returns the constraint violated by it as reported by the validator, or an
+// empty string for a valid input. The validator rejects the input by exiting
+// with a non-zero status, describing the violation on the standard error.
+func (taskConfig TaskConfig) ValidateInput(appConfig ApplicationConfig, input string) (string, error) {
+	if taskConfig.Validator == nil {
+		return "", nil
+	}
+
+	// The processor would report a missing validator by exiting with a
+	// non-zero status, which is not a violation of the constraints.
+	if _, err := os.Stat(taskConfig.ProgramPath(*taskConfig.Validator)); err != nil {
+		return "", err
+	}
+
+	_, err := taskConfig.RunProgram(appConfig, *taskConfig.Validator, input)
+	if exitError, ok := err.(*exec.ExitError); ok {
+		violation := strings.TrimSpace(string(exitError.Stderr))
+		if violation == "" {
+			violation = "invalid input"
+		}
+		return violation, nil
+	}
+	return "", err
+}
+
+// ValidateInputs checks the inputs of all tests of the task.
+func ValidateInputs(taskConfig TaskConfig, appConfig ApplicationConfig) []Problem {
+	problems := make([]Problem, 0)
+	if taskConfig.Validator == nil {
+		return problems
+	}
+
+	tests, err := taskConfig.MaterializeTests(appConfig)
+	if err != nil {
+		return append(problems, Problem{taskConfig.path, err.Error()})
+	}
+
+	for i, test := range tests {
+		violation, err := taskConfig.ValidateInput(appConfig, test.Input)
+		if err != nil {
+			return append(problems, Problem{taskConfig.path, "validator: " + err.Error()})
+		}
+		if violation != "" {
+			problems = append(problems, Problem{taskConfig.path, fmt.Sprintf("test %d: %s", i+1, violation)})
+		}
+	}
+	return problems
+}
+
+// ValidateAllInputs runs ValidateInputs for every task file in the directory.
+func ValidateAllInputs(dir string, appConfig ApplicationConfig) []Problem {
+	problems := make([]Problem, 0)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+Extension))
+	if err != nil {
+		return append(problems, Problem{dir, err.Error()})
+	}
+
+	for _, path := range paths {
+		taskConfig, err := LoadTaskConfig(path)
+		if err != nil {
+			problems = append(problems, Problem{path, err.Error()})
+			continue
+		}
+		problems = append(problems, ValidateInputs(*taskConfig, appConfig)...)
+	}
+	return problems
+}
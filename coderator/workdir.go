@@ -0,0 +1,119 @@
+/*
+ * Copyright (C) 2018 Nikola Trubitsyn
+ *
+ * This file is part of coderator.
+ *
+ * coderator is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * coderator is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with coderator.  If not, see <https://www.gnu.org/licenses/>.
+ */
+
+package coderator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultWorkDir holds the working directories of submissions unless the
+// application config names another one.
+var DefaultWorkDir = filepath.Join(os.TempDir(), "coderator")
+
+// workDirPrefixes are the prefixes of the working directories created by
+// the server.
+var workDirPrefixes = []string{"submission", "hack", "stress", "harness", "suite"}
+
+func (config ApplicationConfig) WorkDirRoot() string {
+	if config.WorkDir == "" {
+		return DefaultWorkDir
+	}
+	return config.WorkDir
+}
+
+func workDirRoot() string {
+	if application == nil {
+		return DefaultWorkDir
+	}
+	return application.WorkDirRoot()
+}
+
+// NewWorkDir creates a new directory in the root readable only by the user
+// running the server.
+func NewWorkDir(root string, prefix string) (string, error) {
+	if err := os.MkdirAll(root, 0700); err != nil {
+		return "", err
+	}
+	return ioutil.TempDir(root, prefix)
+}
+
+// CleanWorkDirs removes the working directories left in the root by a
+// previous run of the server, such as after a crash. Other files in the
+// root are kept.
+func CleanWorkDirs(root string) error {
+	entries, err := ioutil.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !hasWorkDirPrefix(entry.Name()) {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasWorkDirPrefix(name string) bool {
+	for _, prefix := range workDirPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func copyFile(source string, target string) error {
+	data, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(target, data, 0600)
+}
+
+// copyTree copies the regular files of the source directory and of its
+// subdirectories into the target directory.
+func copyTree(source string, target string) error {
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(target, relative), 0700)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, filepath.Join(target, relative))
+	})
+}